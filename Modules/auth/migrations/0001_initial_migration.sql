@@ -1,7 +0,0 @@
--- 0001_initial_migration.sql
--- Add your SQL statements here
-CREATE TABLE IF NOT EXISTS auth (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-);
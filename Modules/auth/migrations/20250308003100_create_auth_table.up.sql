@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS auth (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()), -- Unique identifier for the auth record
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- Timestamp when the record was created
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP -- Timestamp when the record was last updated
+);
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"auto_verse/Modules/auth/routes"
+	"auto_verse/app"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"path/filepath"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+func init() {
+	// Register the auth module with the application
+	app.Register(Module{})
+}
+
+// Module wires the auth module into the application
+type Module struct{}
+
+// Name returns the module name
+func (Module) Name() string {
+	return "auth"
+}
+
+// DependsOn returns the modules that must be booted and migrated before auth
+func (Module) DependsOn() []string {
+	return []string{"users"}
+}
+
+// RegisterRoutes mounts the auth routes on the API router
+func (Module) RegisterRoutes(router *app.Router) {
+	routes.SetupAuthRoutes(router)
+}
+
+// Migrations returns the auth migrations embedded in the binary
+func (Module) Migrations() fs.FS {
+	fsys, _ := fs.Sub(migrationFiles, "migrations")
+	return fsys
+}
+
+// MigrationsDir returns the on-disk directory holding the auth migrations
+func (Module) MigrationsDir() string {
+	return filepath.Join("Modules", "auth", "migrations")
+}
+
+// Init prepares the auth module once the database is available
+func (Module) Init(db *sql.DB) error {
+	return nil
+}
+
+// Shutdown releases resources held by the auth module
+func (Module) Shutdown() error {
+	return nil
+}
@@ -1,13 +1,13 @@
 package routes
 
 import (
-	"net/http"
 	"auto_verse/Modules/auth/controllers"
 	"auto_verse/Modules/auth/middleware"
+	"auto_verse/app"
 )
 
-// SetupAuthRoutes configures routes for the auth module
-func SetupAuthRoutes() {
+// SetupAuthRoutes configures routes for the auth module on the /api/v1 router
+func SetupAuthRoutes(router *app.Router) {
 	controller := controllers.NewAuthController()
-	http.HandleFunc("/auth", middleware.LogRequest(controller.GetHandler))
+	router.HandleFunc("/auth", middleware.LogRequest(controller.GetHandler))
 }
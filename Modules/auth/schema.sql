@@ -0,0 +1,8 @@
+-- Schema of the auth module at version 20250308003100.
+-- Regenerated after migrations run; do not edit by hand.
+
+CREATE TABLE auth (
+    id CHAR(36) NOT NULL DEFAULT (uuid()) PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
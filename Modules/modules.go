@@ -0,0 +1,8 @@
+// Package modules imports every application module for its registration side effects.
+// New imports are appended by make:module when a module is generated.
+package modules
+
+import (
+	_ "auto_verse/Modules/auth"
+	_ "auto_verse/Modules/users"
+)
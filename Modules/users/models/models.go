@@ -6,13 +6,13 @@ import (
 
 // Users represents the core user entity
 type Users struct {
-	ID         string     `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"` 
-	Email      string     `json:"email" gorm:"uniqueIndex;not null"`                        
-	Phone      string     `json:"phone" gorm:"uniqueIndex"`                                  
-	Username   string     `json:"username" gorm:"uniqueIndex;not null"`                      
+	ID         string     `json:"id" gorm:"primaryKey;type:char(36);default:(UUID())"` 
+	Email      string     `json:"email" gorm:"size:255;uniqueIndex;not null"`                        
+	Phone      string     `json:"phone" gorm:"size:15;uniqueIndex"`                                  
+	Username   string     `json:"username" gorm:"size:50;uniqueIndex;not null"`                      
 	Password   string     `json:"-" gorm:"not null"`                                        
-	AuthType   string     `json:"auth_type" gorm:"not null;default:'email'"`                 
-	IsVerified bool       `json:"is_verified" gorm:"default:false"`                          
+	AuthType   string     `json:"auth_type" gorm:"size:50;not null;default:'email'"`                 
+	IsVerified bool       `json:"is_verified" gorm:"not null;default:false"`                          
 	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`                          
 	UpdatedAt  time.Time  `json:"updated_at" gorm:"autoUpdateTime"`                          
 	DeletedAt  *time.Time `json:"deleted_at" gorm:"index"`                                  
@@ -23,17 +23,22 @@ type Users struct {
 
 // UserDetails represents additional details for a user
 type UserDetails struct {
-	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:uuid_generate_v4()"` 
-	UserID      string    `json:"user_id" gorm:"uniqueIndex;not null"`                       
-	FirstName   string    `json:"first_name" gorm:"not null"`                                
-	LastName    string    `json:"last_name" gorm:"not null"`                                 
-	ProfilePic  string    `json:"profile_pic"`                                               
-	Gender      string    `json:"gender"`                                                    
+	ID          string    `json:"id" gorm:"primaryKey;type:char(36);default:(UUID())"` 
+	UserID      string    `json:"user_id" gorm:"type:char(36);uniqueIndex;not null"`                       
+	FirstName   string    `json:"first_name" gorm:"size:50;not null"`                                
+	LastName    string    `json:"last_name" gorm:"size:50;not null"`                                 
+	ProfilePic  string    `json:"profile_pic" gorm:"type:text"`                                               
+	Gender      string    `json:"gender" gorm:"size:10"`                                                    
 	DateOfBirth time.Time `json:"date_of_birth"`                                             
 	AboutMe     string    `json:"about_me" gorm:"type:text"`                                 
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`                          
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`                          
 	
 	// Association with Users
-	User *Users `json:"user" gorm:"foreignKey:UserID"` // Belongs-to relationship with Users (using a pointer)
-}
\ No newline at end of file
+	User *Users `json:"user" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"` // Belongs-to relationship with Users (using a pointer)
+}
+
+// TableName returns the table holding user details
+func (UserDetails) TableName() string {
+	return "users_details"
+}
@@ -0,0 +1,72 @@
+package users
+
+import (
+	"auto_verse/Modules/users/models"
+	"auto_verse/Modules/users/routes"
+	"auto_verse/app"
+	"database/sql"
+	"embed"
+	"io/fs"
+	"path/filepath"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+//go:embed seeds
+var seedFiles embed.FS
+
+func init() {
+	// Register the users module with the application
+	app.Register(Module{})
+}
+
+// Module wires the users module into the application
+type Module struct{}
+
+// Name returns the module name
+func (Module) Name() string {
+	return "users"
+}
+
+// DependsOn returns the modules that must be booted and migrated before users
+func (Module) DependsOn() []string {
+	return nil
+}
+
+// RegisterRoutes mounts the users routes on the API router
+func (Module) RegisterRoutes(router *app.Router) {
+	routes.SetupUsersRoutes(router)
+}
+
+// Migrations returns the users migrations embedded in the binary
+func (Module) Migrations() fs.FS {
+	fsys, _ := fs.Sub(migrationFiles, "migrations")
+	return fsys
+}
+
+// Seeds returns the users seed files, one directory per environment
+func (Module) Seeds() fs.FS {
+	fsys, _ := fs.Sub(seedFiles, "seeds")
+	return fsys
+}
+
+// Models returns the users model structs that migrations are generated from
+func (Module) Models() []any {
+	return []any{models.Users{}, models.UserDetails{}}
+}
+
+// MigrationsDir returns the on-disk directory holding the users migrations
+func (Module) MigrationsDir() string {
+	return filepath.Join("Modules", "users", "migrations")
+}
+
+// Init prepares the users module once the database is available
+func (Module) Init(db *sql.DB) error {
+	return nil
+}
+
+// Shutdown releases resources held by the users module
+func (Module) Shutdown() error {
+	return nil
+}
@@ -0,0 +1,33 @@
+-- Schema of the users module at version 20250308002955.
+-- Regenerated after migrations run; do not edit by hand.
+
+CREATE TABLE users (
+    id CHAR(36) NOT NULL DEFAULT (uuid()) PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    phone VARCHAR(15),
+    username VARCHAR(50) NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    auth_type VARCHAR(50) NOT NULL DEFAULT 'email',
+    is_verified TINYINT(1) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP,
+    UNIQUE INDEX email (email),
+    UNIQUE INDEX phone (phone),
+    UNIQUE INDEX username (username)
+);
+
+CREATE TABLE users_details (
+    id CHAR(36) NOT NULL DEFAULT (uuid()) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL,
+    first_name VARCHAR(50) NOT NULL,
+    last_name VARCHAR(50) NOT NULL,
+    profile_pic TEXT,
+    gender VARCHAR(10),
+    date_of_birth TIMESTAMP,
+    about_me TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE INDEX user_id (user_id),
+    CONSTRAINT users_details_ibfk_1 FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
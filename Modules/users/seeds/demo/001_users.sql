@@ -0,0 +1,13 @@
+-- Demo accounts with profiles for showcasing the API; re-running updates them in place
+-- Passwords are placeholders, not real hashes; reset them through the application
+INSERT INTO users (id, email, phone, username, password, auth_type, is_verified) VALUES
+    ('00000000-0000-0000-0000-0000000000d1', 'jane.doe@example.com', '+10000000101', 'janedoe', 'not-a-real-hash', 'email', TRUE),
+    ('00000000-0000-0000-0000-0000000000d2', 'john.roe@example.com', '+10000000102', 'johnroe', 'not-a-real-hash', 'google', TRUE),
+    ('00000000-0000-0000-0000-0000000000d3', 'sam.poe@example.com', NULL, 'sampoe', 'not-a-real-hash', 'email', FALSE)
+ON DUPLICATE KEY UPDATE email = VALUES(email), phone = VALUES(phone), username = VALUES(username), auth_type = VALUES(auth_type), is_verified = VALUES(is_verified);
+
+INSERT INTO users_details (id, user_id, first_name, last_name, gender, date_of_birth, about_me) VALUES
+    ('00000000-0000-0000-0001-0000000000d1', '00000000-0000-0000-0000-0000000000d1', 'Jane', 'Doe', 'female', '1990-04-12 00:00:00', 'Loves road trips'),
+    ('00000000-0000-0000-0001-0000000000d2', '00000000-0000-0000-0000-0000000000d2', 'John', 'Roe', 'male', '1985-09-30 00:00:00', 'Classic car collector'),
+    ('00000000-0000-0000-0001-0000000000d3', '00000000-0000-0000-0000-0000000000d3', 'Sam', 'Poe', NULL, NULL, NULL)
+ON DUPLICATE KEY UPDATE first_name = VALUES(first_name), last_name = VALUES(last_name), gender = VALUES(gender), date_of_birth = VALUES(date_of_birth), about_me = VALUES(about_me);
@@ -0,0 +1,11 @@
+-- Development accounts; re-running updates them in place
+-- Passwords are placeholders, not real hashes; reset them through the application
+INSERT INTO users (id, email, phone, username, password, auth_type, is_verified) VALUES
+    ('00000000-0000-0000-0000-000000000001', 'admin@example.com', '+10000000001', 'admin', 'not-a-real-hash', 'email', TRUE),
+    ('00000000-0000-0000-0000-000000000002', 'developer@example.com', '+10000000002', 'developer', 'not-a-real-hash', 'email', TRUE)
+ON DUPLICATE KEY UPDATE email = VALUES(email), phone = VALUES(phone), username = VALUES(username), is_verified = VALUES(is_verified);
+
+INSERT INTO users_details (id, user_id, first_name, last_name, gender, about_me) VALUES
+    ('00000000-0000-0000-0001-000000000001', '00000000-0000-0000-0000-000000000001', 'Ada', 'Admin', NULL, 'Development administrator account'),
+    ('00000000-0000-0000-0001-000000000002', '00000000-0000-0000-0000-000000000002', 'Dev', 'Eloper', NULL, 'Development user account')
+ON DUPLICATE KEY UPDATE first_name = VALUES(first_name), last_name = VALUES(last_name), about_me = VALUES(about_me);
@@ -0,0 +1,4 @@
+-- Minimal fixture for automated tests; re-running updates it in place
+INSERT INTO users (id, email, username, password, auth_type, is_verified) VALUES
+    ('00000000-0000-0000-0000-0000000000a1', 'test@example.com', 'test', 'not-a-real-hash', 'email', FALSE)
+ON DUPLICATE KEY UPDATE email = VALUES(email), username = VALUES(username), is_verified = VALUES(is_verified);
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// Boot initialises every registered module after the modules it depends on
+func Boot(db *sql.DB) error {
+	list, err := Ordered()
+	if err != nil {
+		return err
+	}
+
+	for _, module := range list {
+		if err := module.Init(db); err != nil {
+			return fmt.Errorf("failed to initialise module %s: %v", module.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Shutdown stops every registered module in reverse boot order
+func Shutdown() error {
+	list, err := Ordered()
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for i := len(list) - 1; i >= 0; i-- {
+		if err := list[i].Shutdown(); err != nil {
+			log.Printf("Failed to shut down module %s: %v", list[i].Name(), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// MountRoutes registers the routes of every module under /api/v1
+func MountRoutes(router *http.ServeMux) {
+	apiRouter, _ := mountModules()
+	router.Handle(APIPrefix+"/", http.StripPrefix(APIPrefix, apiRouter))
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Module is implemented by every package under Modules/ and registered in its init function
+type Module interface {
+	// Name returns the module name, matching its directory under Modules/
+	Name() string
+
+	// DependsOn returns the names of the modules that must be booted and migrated first
+	DependsOn() []string
+
+	// RegisterRoutes mounts the module's handlers on the /api/v1 router
+	RegisterRoutes(router *Router)
+
+	// Migrations returns the module's migration files embedded in the binary
+	Migrations() fs.FS
+
+	// MigrationsDir returns the on-disk directory holding the same files, used during development
+	MigrationsDir() string
+
+	// Init is called once the database is available, before the server starts
+	Init(db *sql.DB) error
+
+	// Shutdown is called when the application stops
+	Shutdown() error
+}
+
+var (
+	modules = make(map[string]Module)
+	mu      sync.Mutex
+)
+
+// Register adds a module to the application registry
+func Register(module Module) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	name := module.Name()
+	if _, exists := modules[name]; exists {
+		panic(fmt.Sprintf("module %s registered twice", name))
+	}
+	modules[name] = module
+}
+
+// Modules returns all registered modules sorted by name
+func Modules() []Module {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	list := make([]Module, 0, len(names))
+	for _, name := range names {
+		list = append(list, modules[name])
+	}
+	return list
+}
+
+// Ordered returns all registered modules sorted so that every module comes after its dependencies
+func Ordered() ([]Module, error) {
+	return sortModules(Modules())
+}
+
+// sortModules orders modules topologically, breaking ties by name so the order is stable
+func sortModules(list []Module) ([]Module, error) {
+	byName := make(map[string]Module, len(list))
+	for _, module := range list {
+		byName[module.Name()] = module
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(list))
+	sorted := make([]Module, 0, len(list))
+
+	var visit func(module Module, path []string) error
+	visit = func(module Module, path []string) error {
+		name := module.Name()
+		path = append(path, name)
+
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("module dependency cycle: %s", strings.Join(path, " -> "))
+		}
+		state[name] = visiting
+
+		dependencies := append([]string(nil), module.DependsOn()...)
+		sort.Strings(dependencies)
+		for _, dependency := range dependencies {
+			dep, ok := byName[dependency]
+			if !ok {
+				return fmt.Errorf("module %s depends on unregistered module %s", name, dependency)
+			}
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+
+		state[name] = visited
+		sorted = append(sorted, module)
+		return nil
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := visit(byName[name], nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// Get returns the registered module with the given name
+func Get(name string) (Module, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	module, ok := modules[name]
+	if !ok {
+		return nil, fmt.Errorf("module not registered: %s", name)
+	}
+	return module, nil
+}
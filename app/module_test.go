@@ -0,0 +1,67 @@
+package app
+
+import (
+	"database/sql"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+type fakeModule struct {
+	name      string
+	dependsOn []string
+}
+
+func (m fakeModule) Name() string                  { return m.name }
+func (m fakeModule) DependsOn() []string           { return m.dependsOn }
+func (m fakeModule) RegisterRoutes(router *Router) {}
+func (m fakeModule) Migrations() fs.FS             { return nil }
+func (m fakeModule) MigrationsDir() string         { return "" }
+func (m fakeModule) Init(db *sql.DB) error         { return nil }
+func (m fakeModule) Shutdown() error               { return nil }
+
+func names(list []Module) string {
+	parts := make([]string, 0, len(list))
+	for _, module := range list {
+		parts = append(parts, module.Name())
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestSortModules_DependenciesFirst(t *testing.T) {
+	list := []Module{
+		fakeModule{name: "auth", dependsOn: []string{"users"}},
+		fakeModule{name: "billing", dependsOn: []string{"auth", "users"}},
+		fakeModule{name: "users"},
+	}
+
+	sorted, err := sortModules(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "users,auth,billing"
+	if got := names(sorted); got != expected {
+		t.Errorf("Unexpected module order: got %v, want %v", got, expected)
+	}
+}
+
+func TestSortModules_RejectsCycle(t *testing.T) {
+	list := []Module{
+		fakeModule{name: "a", dependsOn: []string{"b"}},
+		fakeModule{name: "b", dependsOn: []string{"a"}},
+	}
+
+	_, err := sortModules(list)
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+}
+
+func TestSortModules_RejectsUnknownDependency(t *testing.T) {
+	list := []Module{fakeModule{name: "auth", dependsOn: []string{"users"}}}
+
+	if _, err := sortModules(list); err == nil {
+		t.Error("Expected an error for an unregistered dependency")
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// APIPrefix is the path every module's routes are mounted under
+const APIPrefix = "/api/v1"
+
+// Route is an HTTP route a module registered on its router
+type Route struct {
+	Module  string `json:"module"`
+	Method  string `json:"method"`  // empty when the route matches every method
+	Pattern string `json:"pattern"` // full path, including APIPrefix
+}
+
+// Router is what modules register their handlers on. It serves through the http.ServeMux it
+// wraps and remembers the patterns registered on it, because http.ServeMux can't list them.
+type Router struct {
+	*http.ServeMux
+	mu       sync.Mutex
+	patterns []string
+}
+
+// NewRouter returns a Router that registers its handlers on mux
+func NewRouter(mux *http.ServeMux) *Router {
+	return &Router{ServeMux: mux}
+}
+
+// Handle registers the handler for the given pattern
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	r.ServeMux.Handle(pattern, handler)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns = append(r.patterns, pattern)
+}
+
+// HandleFunc registers the handler function for the given pattern
+func (r *Router) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	r.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// Walk calls fn with the method and path of every route registered on the router, in the order
+// they were registered, and stops at the first error. The method is empty for routes matching every method.
+func (r *Router) Walk(fn func(method, path string) error) error {
+	r.mu.Lock()
+	patterns := append([]string(nil), r.patterns...)
+	r.mu.Unlock()
+
+	for _, pattern := range patterns {
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			method, path = "", pattern
+		}
+		if err := fn(method, strings.TrimSpace(path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Routes returns the routes every module registers, without serving them
+func Routes() []Route {
+	_, routes := mountModules()
+	return routes
+}
+
+// mountModules registers the routes of every module on a new API router and lists them by walking
+// each module's router once it is mounted
+func mountModules() (*http.ServeMux, []Route) {
+	apiRouter := http.NewServeMux()
+
+	var routes []Route
+	for _, module := range Modules() {
+		router := NewRouter(apiRouter)
+		module.RegisterRoutes(router)
+		router.Walk(func(method, path string) error {
+			routes = append(routes, Route{Module: module.Name(), Method: method, Pattern: APIPrefix + path})
+			return nil
+		})
+	}
+	return apiRouter, routes
+}
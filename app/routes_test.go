@@ -0,0 +1,31 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouter_WalkListsRegisteredRoutes(t *testing.T) {
+	router := NewRouter(http.NewServeMux())
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.HandleFunc("/users", ok)
+	router.HandleFunc("POST /users/{id}", ok)
+	router.Handle("GET /health", http.HandlerFunc(ok))
+
+	var listed []string
+	router.Walk(func(method, path string) error {
+		listed = append(listed, strings.TrimSpace(method+" "+path))
+		return nil
+	})
+	if got := strings.Join(listed, ","); got != "/users,POST /users/{id},GET /health" {
+		t.Errorf("Expected every registered route in order, got %s", got)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/users/7", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the router to serve its routes, got status %d", rr.Code)
+	}
+}
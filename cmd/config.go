@@ -0,0 +1,78 @@
+package main
+
+import (
+	"auto_verse/config"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// setting is one configuration value and the environment variable it comes from
+type setting struct {
+	Env    string `json:"env"`
+	Value  string `json:"value"`
+	Source string `json:"source"` // env when the variable is set, default otherwise
+}
+
+// configCommand prints the configuration the application runs with
+func configCommand(args []string) error {
+	fs := newFlagSet("config")
+	format := fs.String("format", "table", "Output format (table or json)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageErrorf("config", "config takes no arguments")
+	}
+
+	envs := config.Envs
+	settings := []setting{
+		{Env: "PUBLIC_HOST", Value: envs.PublicHost},
+		{Env: "PORT", Value: envs.Port},
+		{Env: "DB_USER", Value: envs.DBUser},
+		{Env: "DB_PASSWORD", Value: mask(envs.DBPassword)},
+		{Env: "DB_HOST", Value: envs.DBAddress},
+		{Env: "DB_NAME", Value: envs.DBName},
+		{Env: "DB_DIALECT", Value: envs.DBDialect},
+		{Env: "TENANTS", Value: envs.Tenants},
+		{Env: "TENANTS_TABLE", Value: envs.TenantsTable},
+		{Env: "TENANT_CONCURRENCY", Value: strconv.FormatInt(envs.TenantConcurrency, 10)},
+		{Env: "MIGRATION_STATEMENT_TIMEOUT", Value: strconv.FormatInt(envs.MigrationTimeout, 10)},
+		{Env: "MIGRATION_LOCK_TIMEOUT", Value: strconv.FormatInt(envs.MigrationLockTimeout, 10)},
+		{Env: "JWT_SECRET", Value: mask(envs.JWTSecret)},
+		{Env: "JWT_EXPIRATION_IN_SECONDS", Value: strconv.FormatInt(envs.JWTExpirationInSeconds, 10)},
+	}
+	for i := range settings {
+		settings[i].Source = "default"
+		if _, ok := os.LookupEnv(settings[i].Env); ok {
+			settings[i].Source = "env"
+		}
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(settings)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+		for _, s := range settings {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Env, s.Value, s.Source)
+		}
+		return w.Flush()
+	default:
+		return usageErrorf("config", "invalid output format: %s", *format)
+	}
+}
+
+// mask hides a secret, showing only whether it is set
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "********"
+}
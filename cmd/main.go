@@ -1,133 +1,239 @@
 package main
 
 import (
-	"auto_verse/Modules/users/routes" // Import the users routes package
+	_ "auto_verse/Modules" // Register every module with the application
 	"auto_verse/config"
-	"auto_verse/migrations"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
 
-	"github.com/go-sql-driver/mysql"
+// Exit codes of the CLI
+const (
+	exitOK      = 0
+	exitFailure = 1 // the command failed, or a check such as lint or drift found problems
+	exitUsage   = 2 // the command line was invalid
 )
 
-var cfg = mysql.Config{
-	User:                 config.Envs.DBUser,
-	Passwd:               config.Envs.DBPassword,
-	Addr:                 config.Envs.DBAddress,
-	DBName:               config.Envs.DBName,
-	Net:                  "tcp",
-	AllowNativePasswords: true,
-	ParseTime:            true,
+var cfg = config.MySQLConfig()
+
+// command is a subcommand of the CLI
+type command struct {
+	name    string
+	args    string // synopsis of the arguments, shown in help
+	summary string
+	details string // printed after the summary by --help
+	run     func(args []string) error
+}
+
+// commands lists the subcommands in the order help shows them
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "[--migrate]", "Boot the modules and start the HTTP server", "", serveCommand},
+		{"migrate", "<operation> [args] [flags]", "Run, inspect or check migrations", migrateDetails, migrateCommand},
+		{"seed", "<env> [flags]", "Load seed data for an environment (dev, test or demo)", "", seedCommand},
+		{"schema", "dump|diff [flags]", "Regenerate or compare the per-module schema.sql snapshots", "", schemaCommand},
+		{"make:module", "<name> [flags]", "Scaffold a new module and register it", "", makeModuleCommand},
+		{"make:migration", "<module> [description] [flags]", "Create an up/down migration pair for a module", makeMigrationDetails, makeMigrationCommand},
+		{"make:models", "<module> <table>[,<table>...] [flags]", "Generate model structs from existing tables", "", makeModelsCommand},
+		{"routes", "[flags]", "List the HTTP routes the modules register", "", routesCommand},
+		{"config", "[flags]", "Print the configuration in effect, with secrets masked", "", configCommand},
+		{"help", "[command]", "Show help for the CLI or a command", "", helpCommand},
+	}
+}
+
+// errChecksFailed is returned by commands that already reported the problems they found
+var errChecksFailed = errors.New("checks failed")
+
+// usageError is a command line mistake; the CLI prints it with the command's usage and exits with exitUsage
+type usageError struct {
+	command string
+	message string
+}
+
+func (e usageError) Error() string { return e.message }
+
+// usageErrorf returns a usageError for a command
+func usageErrorf(name, format string, args ...any) error {
+	return usageError{command: name, message: fmt.Sprintf(format, args...)}
+}
+
+// checkFormat rejects an unknown --format before a command does any work
+func checkFormat(name, format string) error {
+	if format != "table" && format != "json" {
+		return usageErrorf(name, "invalid output format: %s", format)
+	}
+	return nil
 }
 
 func main() {
-	// Parse command-line flags
-	migrateCmd := flag.String("migrate", "", "Run migrations (up, down, or force <version>)")
-	moduleName := flag.String("module", "", "Specify the module to run migrations for (e.g., users, auth)")
-	flag.Parse()
+	os.Exit(run(os.Args[1:]))
+}
 
-	// Connect to the database
-	db, err := connectToDatabase()
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+// run executes the command line and returns the exit code
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+	if args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return exitOK
 	}
-	defer db.Close()
 
-	fmt.Println("Connected to MySQL successfully!")
+	cmd, ok := lookupCommand(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+		printUsage(os.Stderr)
+		return exitUsage
+	}
 
-	// Handle migration commands
-	if *migrateCmd != "" {
-		if err := handleMigrations(db, *migrateCmd, *moduleName); err != nil {
-			log.Fatalf("Migration error: %v", err)
-		}
+	err := cmd.run(args[1:])
+	var usage usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "Error: %s\n", usage.message)
+		fmt.Fprintf(os.Stderr, "Run 'autoverse help %s' for usage.\n", usage.command)
+		return exitUsage
+	case errors.Is(err, errChecksFailed):
+		return exitFailure
+	default:
+		log.Printf("%s: %v", cmd.name, err)
+		return exitFailure
 	}
+}
 
-	// Start the application (e.g., HTTP server)
-	startApplication()
+// lookupCommand finds a subcommand by name
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
 }
 
-// connectToDatabase establishes a connection to the MySQL database
-func connectToDatabase() (*sql.DB, error) {
-	db, err := sql.Open("mysql", cfg.FormatDSN())
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %v", err)
+// printUsage prints the list of commands
+func printUsage(w *os.File) {
+	fmt.Fprintln(w, "AutoVerse command line")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage: autoverse <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'autoverse help <command>' for the arguments and flags of a command.")
+	fmt.Fprintln(w, "Exit codes: 0 success, 1 failure or failed checks, 2 invalid usage.")
+}
 
-	// Ping the database to verify the connection
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %v", err)
+// helpCommand prints the CLI usage, or the usage of one command
+func helpCommand(args []string) error {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return nil
+	}
+	if len(args) > 1 {
+		return usageErrorf("help", "help takes at most one command")
+	}
+	cmd, ok := lookupCommand(args[0])
+	if !ok {
+		return usageErrorf("help", "unknown command %q", args[0])
+	}
+	if cmd.name == "help" {
+		printUsage(os.Stdout)
+		return nil
 	}
+	// Every command prints its usage and flags for --help
+	return cmd.run([]string{"--help"})
+}
 
-	return db, nil
+// newFlagSet returns the flag set of a command
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	// parseArgs reports flag errors and help itself, so keep the flag package quiet
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	return fs
 }
 
-// handleMigrations processes migration commands
-func handleMigrations(db *sql.DB, migrateCmd, moduleName string) error {
-	switch migrateCmd {
-	case "up":
-		return applyMigrations(db, moduleName)
-	case "down":
-		return rollbackMigrations(db, moduleName)
-	default:
-		return fmt.Errorf("invalid migration command: %s", migrateCmd)
+// printCommandUsage prints the usage, details and flags of a command
+func printCommandUsage(fs *flag.FlagSet) {
+	cmd, _ := lookupCommand(fs.Name())
+	fmt.Printf("Usage: autoverse %s %s\n\n%s\n", cmd.name, cmd.args, cmd.summary)
+	if cmd.details != "" {
+		fmt.Printf("\n%s\n", strings.TrimSpace(cmd.details))
 	}
+	fmt.Printf("\nFlags:\n")
+	fs.SetOutput(os.Stdout)
+	fs.PrintDefaults()
+	fs.SetOutput(io.Discard)
 }
 
-// applyMigrations applies migrations for a specific module or all modules
-func applyMigrations(db *sql.DB, moduleName string) error {
-	if moduleName != "" {
-		// Run migrations for a specific module
-		if err := migrations.RunForModule(db, moduleName, "up"); err != nil {
-			return fmt.Errorf("failed to apply migrations for module %s: %v", moduleName, err)
+// parseArgs parses a command's flags, which may come before, between or after its arguments,
+// and returns the arguments. Negative numbers, as in "migrate steps -2", are arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 {
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
 		}
-	} else {
-		// Run migrations for all modules
-		if err := migrations.RunAll(db); err != nil {
-			return fmt.Errorf("failed to apply migrations (up): %v", err)
+		if !strings.HasPrefix(args[0], "-") || args[0] == "-" || isNumber(args[0]) {
+			positional = append(positional, args[0])
+			args = args[1:]
+			continue
 		}
-	}
-	fmt.Println("Migrations applied successfully!")
-	return nil
-}
 
-// rollbackMigrations rolls back migrations for a specific module or all modules
-func rollbackMigrations(db *sql.DB, moduleName string) error {
-	if moduleName != "" {
-		// Rollback migrations for a specific module
-		if err := migrations.RunForModule(db, moduleName, "down"); err != nil {
-			return fmt.Errorf("failed to rollback migrations for module %s: %v", moduleName, err)
+		// Parse the flags up to the next argument flag would mistake for a flag
+		end := len(args)
+		for i := 1; i < len(args); i++ {
+			if args[i] == "--" || (strings.HasPrefix(args[i], "-") && isNumber(args[i])) {
+				end = i
+				break
+			}
 		}
-	} else {
-		// Rollback migrations for all modules
-		if err := migrations.RollbackAll(db); err != nil {
-			return fmt.Errorf("failed to rollback migrations (down): %v", err)
+		if err := fs.Parse(args[:end]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				printCommandUsage(fs)
+				return nil, err
+			}
+			return nil, usageErrorf(fs.Name(), "%v", err)
 		}
+		args = append(fs.Args(), args[end:]...)
 	}
-	fmt.Println("Migrations rolled back successfully!")
-	return nil
+	return positional, nil
 }
 
-// startApplication starts the application (e.g., HTTP server)
-func startApplication() {
-	fmt.Println("Starting the application...")
-
-	// Create a new ServeMux for routing
-	router := http.NewServeMux()
-
-	// Setup routes for the users module
-	routes.SetupUsersRoutes(router)
+// isNumber reports whether an argument is an integer
+func isNumber(arg string) bool {
+	_, err := strconv.Atoi(arg)
+	return err == nil
+}
 
-	// Default route
-	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintf(w, "Welcome to AutoVerse!")
-	})
+// connectToDatabase establishes a connection to the MySQL database
+func connectToDatabase() (*sql.DB, error) {
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
 
-	port := ":8080"
-	fmt.Printf("Server is running on http://localhost%s\n", port)
-	if err := http.ListenAndServe(port, router); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// Ping the database to verify the connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
+
+	log.Println("Connected to MySQL successfully!")
+	return db, nil
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"auto_verse/migrations"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseArgs_FlagsAnywhere(t *testing.T) {
+	fs := newFlagSet("migrate")
+	module := fs.String("module", "", "")
+	dryRun := fs.Bool("dry-run", false, "")
+
+	args, err := parseArgs(fs, []string{"steps", "--module", "users", "-2", "--dry-run"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(args, " "); got != "steps -2" {
+		t.Errorf("Unexpected arguments: got %q, want %q", got, "steps -2")
+	}
+	if *module != "users" || !*dryRun {
+		t.Errorf("Flags not parsed: module=%q dry-run=%v", *module, *dryRun)
+	}
+}
+
+func TestParseArgs_UnknownFlagIsUsageError(t *testing.T) {
+	fs := newFlagSet("migrate")
+
+	_, err := parseArgs(fs, []string{"up", "--nope"})
+	var usage usageError
+	if !errors.As(err, &usage) {
+		t.Errorf("Expected a usage error, got %v", err)
+	}
+}
+
+func TestCheckOperation(t *testing.T) {
+	valid := [][]string{{"up"}, {"up", "2"}, {"down", "1"}, {"steps", "-3"}, {"goto", "20240101000000"}, {"force", "-1"}, {"history"}, {"lint"}}
+	for _, args := range valid {
+		if err := checkOperation(args); err != nil {
+			t.Errorf("checkOperation(%v) = %v, want nil", args, err)
+		}
+	}
+
+	invalid := [][]string{nil, {"sideways"}, {"steps"}, {"steps", "0"}, {"goto", "-1"}, {"history", "-5"}, {"status", "extra"}}
+	for _, args := range invalid {
+		var usage usageError
+		if err := checkOperation(args); !errors.As(err, &usage) {
+			t.Errorf("checkOperation(%v) = %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestRun_InvalidFormatIsUsageError(t *testing.T) {
+	// None of these may reach the database: the format is checked right after the flags
+	for _, args := range [][]string{
+		{"migrate", "status", "--format", "yaml"},
+		{"migrate", "up", "--dry-run", "--format", "yaml"},
+		{"seed", "dev", "--format", "yaml"},
+		{"schema", "diff", "--format", "yaml"},
+	} {
+		if code := run(args); code != exitUsage {
+			t.Errorf("run(%v) = %d, want %d", args, code, exitUsage)
+		}
+	}
+}
+
+func TestDumpsSchema_OnlyWithTheFlag(t *testing.T) {
+	tests := []struct {
+		command    string
+		dumpSchema bool
+		want       bool
+	}{
+		{"up", true, true},
+		{"up", false, false},
+		{"contract", true, true},
+		{"contract", false, false},
+		{"down", true, true},
+		{"steps", false, false},
+		{"goto", true, true},
+		{"status", true, false},
+		{"force", true, false},
+	}
+	for _, tt := range tests {
+		if got := dumpsSchema(tt.command, tt.dumpSchema); got != tt.want {
+			t.Errorf("dumpsSchema(%q, %v) = %v, want %v", tt.command, tt.dumpSchema, got, tt.want)
+		}
+	}
+}
+
+func TestAdoptsLegacyVersions_OnlyMutatingCommands(t *testing.T) {
+	for _, command := range []string{"up", "down", "steps", "goto", "force", "contract"} {
+		if !adoptsLegacyVersions(command, false) {
+			t.Errorf("Expected %s to adopt legacy versions", command)
+		}
+		if adoptsLegacyVersions(command, true) {
+			t.Errorf("Expected a dry run of %s to leave legacy versions alone", command)
+		}
+	}
+	for _, command := range []string{"status", "history", "drift", "lint", "baseline", "squash"} {
+		if adoptsLegacyVersions(command, false) {
+			t.Errorf("Expected %s to leave legacy versions alone", command)
+		}
+	}
+}
+
+func TestCheckTenantOperation(t *testing.T) {
+	for _, args := range [][]string{{"up"}, {"down"}, {"status"}, {"contract"}, {"force", "20250101000000"}} {
+		if err := checkTenantOperation(args, false); err != nil {
+			t.Errorf("checkTenantOperation(%v) = %v, want nil", args, err)
+		}
+	}
+
+	for _, args := range [][]string{{"steps", "2"}, {"goto", "20250101000000"}, {"up", "2"}, {"history"}} {
+		err := checkTenantOperation(args, false)
+		var usage usageError
+		if !errors.As(err, &usage) || !strings.Contains(usage.message, args[0]) {
+			t.Errorf("checkTenantOperation(%v) = %v, want a usage error naming the command", args, err)
+		}
+	}
+	if err := checkTenantOperation([]string{"up"}, true); err == nil {
+		t.Error("Expected --dry-run to be rejected with --tenants")
+	}
+}
+
+func TestWriteMigrationPlan(t *testing.T) {
+	var empty bytes.Buffer
+	if err := writeMigrationPlan(&empty, nil, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(empty.String()); got != "[]" {
+		t.Errorf("Expected an empty plan to print [] as JSON, got %q", got)
+	}
+
+	plan := []migrations.PlannedMigration{
+		{Module: "users", Version: 2, Direction: "up", File: "2_add_name.up.sql", SQL: "ALTER TABLE users ADD COLUMN name TEXT;\n"},
+		{Module: "users", Version: 1, Direction: "down"},
+	}
+	var table bytes.Buffer
+	if err := writeMigrationPlan(&table, plan, "table"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "-- [1/2] users 2 up: 2_add_name.up.sql\nALTER TABLE users ADD COLUMN name TEXT;\n\n" +
+		"-- [2/2] users 1 down: \n-- (no down file; only the version would change)\n\n"
+	if table.String() != expected {
+		t.Errorf("Expected the plan table\n%s\ngot\n%s", expected, table.String())
+	}
+
+	var decoded []migrations.PlannedMigration
+	var encoded bytes.Buffer
+	if err := writeMigrationPlan(&encoded, plan, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(encoded.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[0].File != "2_add_name.up.sql" {
+		t.Errorf("Expected the plan to round-trip through JSON, got %+v, %v", decoded, err)
+	}
+}
+
+func TestWriteMigrationStatus(t *testing.T) {
+	version := uint(2)
+	statuses := []migrations.ModuleStatus{
+		{Module: "users", Version: &version, Pending: []string{"3_drop_name.up.sql"}, Contract: []string{"3_drop_name.up.sql"}},
+		{Module: "auth", Dirty: true, Pending: []string{}, Contract: []string{}},
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{"table", "table", "MODULE  VERSION  DIRTY  PENDING  CONTRACT\n" +
+			"users   2        false  1        1\n" +
+			"auth    none     true   0        0\n" +
+			"\nPending migrations for users:\n  - 3_drop_name.up.sql\n" +
+			"\nOutstanding contract migrations for users (run migrate contract once every instance is upgraded):\n  - 3_drop_name.up.sql\n"},
+		{"json", "json", `[
+  {
+    "module": "users",
+    "version": 2,
+    "dirty": false,
+    "pending": [
+      "3_drop_name.up.sql"
+    ],
+    "contract": [
+      "3_drop_name.up.sql"
+    ]
+  },
+  {
+    "module": "auth",
+    "version": null,
+    "dirty": true,
+    "pending": [],
+    "contract": []
+  }
+]
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := writeMigrationStatus(&out, statuses, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("Expected the status\n%s\ngot\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
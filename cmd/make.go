@@ -0,0 +1,174 @@
+package main
+
+import (
+	"auto_verse/config"
+	"auto_verse/helpers"
+	"auto_verse/migrations"
+	"auto_verse/schema"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const makeMigrationDetails = `
+The description may be left out when --create or --table describes the migration.
+With --from-models the migration is generated from the differences between the
+module's models and the database, and the description is required.
+
+Examples:
+  autoverse make:migration users add_phone --table=users --add-column=phone:VARCHAR(15)
+  autoverse make:migration blog --create=posts --add-column=title:VARCHAR(255) --add-fk=user_id:users.id:cascade`
+
+// stringList collects the values of a flag that may be repeated
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// makeModuleCommand scaffolds a new module
+func makeModuleCommand(args []string) error {
+	fs := newFlagSet("make:module")
+	dialectName := fs.String("dialect", config.Envs.DBDialect, "Database dialect to write the initial migration for (mysql, postgres or sqlite)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageErrorf("make:module", "make:module takes one module name")
+	}
+	dialect, err := schema.LookupDialect(*dialectName)
+	if err != nil {
+		return usageErrorf("make:module", "%v", err)
+	}
+
+	paths, err := helpers.CreateModule(args[0], dialect)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		fmt.Printf("Created %s\n", path)
+	}
+	fmt.Printf("Module '%s' created successfully!\n", args[0])
+	return nil
+}
+
+// makeMigrationCommand creates an up/down migration pair for a module
+func makeMigrationCommand(args []string) error {
+	var opts migrations.MigrationOptions
+	var columns, indexes, foreignKeys stringList
+	fs := newFlagSet("make:migration")
+	fromModels := fs.Bool("from-models", false, "Generate the migration from the differences between the module's models and the database")
+	dialect := fs.String("dialect", config.Envs.DBDialect, "Database dialect to write SQL for (mysql, postgres or sqlite)")
+	fs.StringVar(&opts.Create, "create", "", "Create this table")
+	fs.StringVar(&opts.Table, "table", "", "Alter this existing table")
+	fs.Var(&columns, "add-column", "Add a column as name:type, e.g. phone:VARCHAR(15) (repeatable)")
+	fs.Var(&indexes, "add-index", "Add an index on comma separated columns (repeatable)")
+	fs.BoolVar(&opts.Unique, "unique", false, "Make the added indexes unique")
+	fs.Var(&foreignKeys, "add-fk", "Add a foreign key as column:table.column[:on_delete], e.g. user_id:users.id:cascade (repeatable)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	opts.AddColumns, opts.AddIndexes, opts.AddForeignKeys = columns, indexes, foreignKeys
+
+	// The description may be left out when --create or --table describes the migration
+	described := opts.Create != "" || opts.Table != ""
+	if len(args) < 1 || len(args) > 2 || (len(args) < 2 && (*fromModels || !described)) {
+		return usageErrorf("make:migration", "make:migration takes a module name and a migration description")
+	}
+	if *fromModels && (described || len(columns)+len(indexes)+len(foreignKeys) > 0) {
+		return usageErrorf("make:migration", "--from-models cannot be combined with --create, --table or --add-* options")
+	}
+	if err := migrations.SetDialect(*dialect); err != nil {
+		return usageErrorf("make:migration", "%v", err)
+	}
+	moduleName := args[0]
+	migrationDescription := ""
+	if len(args) > 1 {
+		migrationDescription = args[1]
+	}
+
+	if !*fromModels {
+		paths, err := migrations.CreateMigration(moduleName, migrationDescription, opts)
+		if err != nil {
+			return fmt.Errorf("failed to create migration: %v", err)
+		}
+		fmt.Printf("Migration files created:\n- %s\n- %s\n", paths[0], paths[1])
+		return nil
+	}
+
+	db, err := connectToDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	paths, err := migrations.CreateMigrationFromModels(db, moduleName, migrationDescription)
+	if err != nil {
+		return fmt.Errorf("failed to generate migration: %v", err)
+	}
+	if len(paths) == 0 {
+		fmt.Printf("The %s models match the database; no migration created\n", moduleName)
+		return nil
+	}
+	fmt.Printf("Migration files created:\n- %s\n- %s\n", paths[0], paths[1])
+	return nil
+}
+
+// makeModelsCommand generates model structs for existing tables
+func makeModelsCommand(args []string) error {
+	fs := newFlagSet("make:models")
+	force := fs.Bool("force", false, "Overwrite model files that already exist")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
+		return usageErrorf("make:models", "make:models takes a module name and the tables to generate models for")
+	}
+	moduleName := args[0]
+	var names []string
+	for _, arg := range args[1:] {
+		for _, name := range strings.Split(arg, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	db, err := connectToDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tables, err := schema.Inspect(db, names)
+	if err != nil {
+		return fmt.Errorf("failed to inspect tables: %v", err)
+	}
+	if len(tables) != len(names) {
+		found := make(map[string]bool)
+		for _, table := range tables {
+			found[table.Name] = true
+		}
+		for _, name := range names {
+			if !found[name] {
+				return fmt.Errorf("table %s does not exist in database %s", name, cfg.DBName)
+			}
+		}
+	}
+
+	paths, err := schema.WriteModels(filepath.Join("Modules", moduleName, "models"), tables, *force)
+	if err != nil {
+		return fmt.Errorf("failed to write models: %v", err)
+	}
+	fmt.Println("Model files created:")
+	for _, path := range paths {
+		fmt.Printf("- %s\n", path)
+	}
+	return nil
+}
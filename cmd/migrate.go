@@ -0,0 +1,993 @@
+package main
+
+import (
+	"auto_verse/config"
+	"auto_verse/migrations"
+	"auto_verse/seeds"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateDetails = `
+Operations:
+  up [N]              Apply all pending migrations, or the next N
+  down [N]            Roll back all migrations, or the last N
+  steps N             Move N steps up (N > 0) or down (N < 0)
+  goto <version>      Migrate up or down to a version
+  force <version>     Record a version without running migrations (-1 for none, with --module)
+  contract            Apply the held contract-phase migrations
+  baseline <version>  Record an existing database as migrated to a version (with --module)
+  squash <version>    Replace the migrations up to a version with a baseline (with --module)
+  status              Show the applied and pending migrations
+  history [N]         Show the last N migration runs (default 50)
+  drift               List applied migrations whose files changed; exits 1 when there are any
+  lint                Check the migration files without a database; exits 1 on errors
+
+up, down, status, contract and force can run in tenant schemas with --tenants.`
+
+// migrateCommand runs a migration operation
+func migrateCommand(args []string) error {
+	fs := newFlagSet("migrate")
+	moduleName := fs.String("module", "", "Run the operation for this module only (e.g., users, auth)")
+	format := fs.String("format", "table", "Output format for migration reports (table or json)")
+	dryRun := fs.Bool("dry-run", false, "Print the migrations that would run, with their SQL, without applying them")
+	fromDisk := fs.Bool("migrations-from-disk", false, "Read migrations from Modules/<name>/migrations instead of the files embedded in the binary")
+	onDrift := fs.String("on-drift", migrations.DriftRefuse, "What to do when an applied migration file has changed (refuse or warn)")
+	dumpSchema := fs.Bool("dump-schema", false, "Regenerate the schema.sql snapshots after up, contract, down, steps or goto")
+	tenantsFlag := fs.String("tenants", "", "Run up, down, status, contract or force in tenant schemas: a comma separated list, or all for TENANTS or TENANTS_TABLE")
+	tenantConcurrency := fs.Int("tenant-concurrency", int(config.Envs.TenantConcurrency), "Number of tenant schemas migrated at once")
+	statementTimeout := fs.Duration("statement-timeout", time.Duration(config.Envs.MigrationTimeout)*time.Second, "Longest a single SQL migration may run, e.g. 5m (0 for no limit)")
+	lockTimeout := fs.Duration("lock-timeout", time.Duration(config.Envs.MigrationLockTimeout)*time.Second, "Longest to wait for the migration lock and table locks, e.g. 30s (0 for the defaults)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := checkOperation(args); err != nil {
+		return err
+	}
+	if err := checkFormat("migrate", *format); err != nil {
+		return err
+	}
+	if *tenantsFlag != "" {
+		if err := checkTenantOperation(args, *dryRun); err != nil {
+			return err
+		}
+	}
+	if err := migrations.SetDriftPolicy(*onDrift); err != nil {
+		return usageErrorf("migrate", "%v", err)
+	}
+	migrations.UseDiskSource(*fromDisk)
+	// Lint checks the SQL against the configured database, which the runner also requires to be mysql
+	if err := migrations.SetDialect(config.Envs.DBDialect); err != nil {
+		return fmt.Errorf("invalid DB_DIALECT: %v", err)
+	}
+
+	// Ctrl+C interrupts a migration run: no further migration starts and the running statement is killed
+	ctx, stopInterrupts := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stopInterrupts()
+	migrations.UseContext(ctx)
+	migrations.SetTimeouts(*statementTimeout, *lockTimeout)
+
+	// Linting only reads the migration files, so it doesn't need a database
+	if args[0] == "lint" {
+		ok, err := lintMigrations(*moduleName, *format)
+		if err == nil && !ok {
+			err = errChecksFailed
+		}
+		return err
+	}
+
+	db, err := connectToDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	adoptLegacy := adoptsLegacyVersions(args[0], *dryRun)
+	if adoptLegacy {
+		if err := migrations.AdoptLegacyVersions(db); err != nil {
+			return err
+		}
+	}
+
+	// Tenant schemas live on the same server as the configured database
+	migrations.UseTenants(func(schema string) (*sql.DB, error) {
+		tenantCfg := cfg
+		tenantCfg.DBName = schema
+		tenantDB, err := config.SQLStorage(tenantCfg)
+		if err != nil {
+			return nil, err
+		}
+		if adoptLegacy {
+			if err := migrations.AdoptLegacyVersions(tenantDB); err != nil {
+				tenantDB.Close()
+				return nil, err
+			}
+		}
+		return tenantDB, nil
+	}, *tenantConcurrency)
+	if *tenantsFlag != "" {
+		tenants, err := resolveTenants(db, *tenantsFlag)
+		if err != nil {
+			return err
+		}
+		return handleTenantMigrations(db, args, *moduleName, *format, tenants)
+	}
+
+	if *dryRun {
+		return printMigrationPlan(db, args, *moduleName, *format)
+	}
+
+	if args[0] == "drift" {
+		ok, err := printMigrationDrift(db, *moduleName, *format)
+		if err == nil && !ok {
+			err = errChecksFailed
+		}
+		return err
+	}
+
+	if err := handleMigrations(db, args, *moduleName, *format); err != nil {
+		return err
+	}
+
+	// Keep the committed schema snapshots in step with the migrations that just ran
+	if dumpsSchema(args[0], *dumpSchema) {
+		if _, err := dumpSchemas(db, *moduleName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTenantOperation rejects the operations that --tenants can't run in tenant schemas
+func checkTenantOperation(args []string, dryRun bool) error {
+	if dryRun {
+		return usageErrorf("migrate", "--tenants cannot be combined with --dry-run")
+	}
+	switch {
+	case len(args) == 1 && slices.Contains([]string{"up", "down", "status", "contract"}, args[0]):
+		return nil
+	case args[0] == "force":
+		return nil
+	}
+	return usageErrorf("migrate", "migrate %s is not supported with --tenants; use up, down, status, contract or force "+
+		"(up N, down N, steps, goto, baseline, squash, history, drift and lint run against DB_NAME only)", strings.Join(args, " "))
+}
+
+// checkOperation validates the operation and its arguments before anything connects to the database
+func checkOperation(args []string) error {
+	if len(args) == 0 {
+		return usageErrorf("migrate", "no migration operation given")
+	}
+
+	var err error
+	switch args[0] {
+	case "up", "down", "history":
+		if len(args) > 1 {
+			_, err = parseSteps(args)
+		}
+	case "steps":
+		_, err = parseSteps(args)
+	case "goto", "force", "baseline", "squash":
+		_, err = parseVersion(args)
+	case "contract", "status", "drift", "lint":
+		if len(args) > 1 {
+			err = usageErrorf("migrate", "%s takes no arguments", args[0])
+		}
+	default:
+		err = usageErrorf("migrate", "invalid migration operation: %s", args[0])
+	}
+	if err == nil && args[0] == "history" && len(args) > 1 && args[1][0] == '-' {
+		err = usageErrorf("migrate", "invalid entry count for history: %s", args[1])
+	}
+	return err
+}
+
+// seedCommand loads seed data for an environment
+func seedCommand(args []string) error {
+	fs := newFlagSet("seed")
+	moduleName := fs.String("module", "", "Load the seeds of this module only")
+	format := fs.String("format", "table", "Output format for the seed report (table or json)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageErrorf("seed", "seed takes one environment (dev, test or demo)")
+	}
+	if err := checkFormat("seed", *format); err != nil {
+		return err
+	}
+
+	db, err := connectToDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return runSeeds(db, args[0], *moduleName, *format)
+}
+
+// schemaCommand regenerates or compares the schema snapshots
+func schemaCommand(args []string) error {
+	fs := newFlagSet("schema")
+	moduleName := fs.String("module", "", "Dump or compare the snapshot of this module only")
+	format := fs.String("format", "table", "Output format for schema diff (table or json)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 || (args[0] != "dump" && args[0] != "diff") {
+		return usageErrorf("schema", "schema takes dump or diff")
+	}
+	if err := checkFormat("schema", *format); err != nil {
+		return err
+	}
+
+	db, err := connectToDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ok, err := handleSchema(db, args[0], *moduleName, *format)
+	if err == nil && !ok {
+		err = errChecksFailed
+	}
+	return err
+}
+
+// handleMigrations processes migration commands
+func handleMigrations(db *sql.DB, args []string, moduleName, format string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("no migration command given")
+	}
+
+	switch args[0] {
+	case "up":
+		if len(args) > 1 {
+			n, err := parseSteps(args)
+			if err != nil {
+				return err
+			}
+			return stepMigrations(db, moduleName, n)
+		}
+		return applyMigrations(db, moduleName, nil)
+	case "contract":
+		return contractMigrations(db, moduleName, nil)
+	case "down":
+		if len(args) > 1 {
+			n, err := parseSteps(args)
+			if err != nil {
+				return err
+			}
+			return stepMigrations(db, moduleName, -n)
+		}
+		return rollbackMigrations(db, moduleName, nil)
+	case "steps":
+		n, err := parseSteps(args)
+		if err != nil {
+			return err
+		}
+		return stepMigrations(db, moduleName, n)
+	case "goto":
+		version, err := parseVersion(args)
+		if err != nil {
+			return err
+		}
+		return gotoVersion(db, moduleName, uint(version))
+	case "force":
+		version, err := parseVersion(args)
+		if err != nil {
+			return err
+		}
+		return forceVersion(db, moduleName, version, nil)
+	case "status":
+		return printMigrationStatus(db, moduleName, format)
+	case "history":
+		limit := 50
+		if len(args) > 1 {
+			n, err := parseSteps(args)
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid entry count for history: %s", args[1])
+			}
+			limit = n
+		}
+		return printMigrationHistory(db, moduleName, limit, format)
+	case "baseline":
+		version, err := parseVersion(args)
+		if err != nil {
+			return err
+		}
+		if moduleName == "" {
+			return usageErrorf("migrate", "baseline requires --module")
+		}
+		if err := migrations.Baseline(db, moduleName, uint(version)); err != nil {
+			return err
+		}
+		fmt.Printf("Baselined module %s at version %d successfully!\n", moduleName, version)
+		return nil
+	case "squash":
+		version, err := parseVersion(args)
+		if err != nil {
+			return err
+		}
+		return squashMigrations(db, moduleName, uint(version))
+	default:
+		return fmt.Errorf("invalid migration command: %s", args[0])
+	}
+}
+
+// handleTenantMigrations processes the migration commands that can run in tenant schemas
+func handleTenantMigrations(db *sql.DB, args []string, moduleName, format string, tenants []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "up":
+		return applyMigrations(db, moduleName, tenants)
+	case len(args) == 1 && args[0] == "down":
+		return rollbackMigrations(db, moduleName, tenants)
+	case len(args) == 1 && args[0] == "status":
+		return printTenantStatus(tenants, moduleName, format)
+	case len(args) == 1 && args[0] == "contract":
+		return contractMigrations(db, moduleName, tenants)
+	case args[0] == "force":
+		version, err := parseVersion(args)
+		if err != nil {
+			return err
+		}
+		return forceVersion(db, moduleName, version, tenants)
+	default:
+		return checkTenantOperation(args, false)
+	}
+}
+
+// resolveTenants turns the --tenants flag into schema names: a comma separated list,
+// or all for the TENANTS setting or, when that is empty, the schemas in TENANTS_TABLE
+func resolveTenants(db *sql.DB, value string) ([]string, error) {
+	if value == "all" {
+		value = config.Envs.Tenants
+		if value == "" {
+			if config.Envs.TenantsTable == "" {
+				return nil, fmt.Errorf("--tenants all needs TENANTS or TENANTS_TABLE to be set")
+			}
+			tenants, err := migrations.LoadTenants(db, config.Envs.TenantsTable)
+			if err == nil && len(tenants) == 0 {
+				err = fmt.Errorf("no tenants found in %s", config.Envs.TenantsTable)
+			}
+			return tenants, err
+		}
+	}
+
+	var tenants []string
+	for _, tenant := range strings.Split(value, ",") {
+		if tenant = strings.TrimSpace(tenant); tenant != "" {
+			tenants = append(tenants, tenant)
+		}
+	}
+	if len(tenants) == 0 {
+		return nil, fmt.Errorf("no tenants given")
+	}
+	return tenants, nil
+}
+
+// parseOperation converts migration command arguments into an operation that can be planned
+func parseOperation(args []string) (migrations.Operation, error) {
+	if len(args) == 0 {
+		return migrations.Operation{}, fmt.Errorf("no migration command given")
+	}
+
+	switch args[0] {
+	case "contract":
+		return migrations.Operation{Command: "contract"}, nil
+	case "up", "down":
+		if len(args) == 1 {
+			return migrations.Operation{Command: args[0]}, nil
+		}
+		n, err := parseSteps(args)
+		if err != nil {
+			return migrations.Operation{}, err
+		}
+		if args[0] == "down" {
+			n = -n
+		}
+		return migrations.Operation{Command: "steps", Steps: n}, nil
+	case "steps":
+		n, err := parseSteps(args)
+		return migrations.Operation{Command: "steps", Steps: n}, err
+	case "goto":
+		version, err := parseVersion(args)
+		return migrations.Operation{Command: "goto", Version: uint(version)}, err
+	default:
+		return migrations.Operation{}, fmt.Errorf("dry run is not supported for migration command: %s", args[0])
+	}
+}
+
+// parseSteps reads the step count argument of up, down and steps
+func parseSteps(args []string) (int, error) {
+	if len(args) != 2 {
+		return 0, usageErrorf("migrate", "usage: %s N", args[0])
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n == 0 {
+		return 0, usageErrorf("migrate", "invalid step count for %s: %s", args[0], args[1])
+	}
+	return n, nil
+}
+
+// parseVersion reads the version argument of goto and force
+func parseVersion(args []string) (int, error) {
+	if len(args) != 2 {
+		return 0, usageErrorf("migrate", "usage: %s <version>", args[0])
+	}
+	version, err := strconv.Atoi(args[1])
+	if err != nil || version < -1 || (version == -1 && args[0] != "force") {
+		return 0, usageErrorf("migrate", "invalid version for %s: %s", args[0], args[1])
+	}
+	return version, nil
+}
+
+// stepMigrations moves a specific module or all modules n steps up (n > 0) or down (n < 0)
+func stepMigrations(db *sql.DB, moduleName string, n int) error {
+	if moduleName != "" {
+		if err := migrations.StepsForModule(db, moduleName, n); err != nil {
+			return err
+		}
+	} else {
+		if err := migrations.StepsAll(db, n); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Migrated %d step(s) successfully!\n", n)
+	return nil
+}
+
+// gotoVersion migrates a specific module or all modules to a version
+func gotoVersion(db *sql.DB, moduleName string, version uint) error {
+	if moduleName != "" {
+		if err := migrations.GotoForModule(db, moduleName, version); err != nil {
+			return err
+		}
+	} else {
+		if err := migrations.GotoAll(db, version); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Migrated to version %d successfully!\n", version)
+	return nil
+}
+
+// forceVersion records a version for a specific module or all modules without running migrations,
+// in each tenant schema when tenants are given
+func forceVersion(db *sql.DB, moduleName string, version int, tenants []string) error {
+	if moduleName != "" {
+		if err := migrations.ForceForModule(db, moduleName, version, tenants...); err != nil {
+			return err
+		}
+	} else {
+		if version < 0 {
+			return usageErrorf("migrate", "forcing version -1 requires --module")
+		}
+		if err := migrations.ForceAll(db, uint(version), tenants...); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Forced version %d successfully!\n", version)
+	return nil
+}
+
+// squashMigrations replaces a module's migrations up to a version with a baseline built in a scratch database
+func squashMigrations(db *sql.DB, moduleName string, version uint) error {
+	if moduleName == "" {
+		return usageErrorf("migrate", "squash requires --module")
+	}
+
+	var result migrations.SquashResult
+	err := withScratchDatabase(db, func(scratch *sql.DB) error {
+		var err error
+		result, err = migrations.Squash(db, scratch, moduleName, version)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, path := range result.Removed {
+		fmt.Printf("Removed %s\n", path)
+	}
+	for _, path := range result.Written {
+		fmt.Printf("Created %s\n", path)
+	}
+	for _, file := range result.GoMigrations {
+		fmt.Printf("Go migration %s is covered by the baseline; remove its registration from Modules/%s/migrate.go\n", file, moduleName)
+	}
+	if result.Adopted {
+		fmt.Printf("The database is past version %d and was marked compatible with the baseline.\n", version)
+	}
+	fmt.Printf("Squashed module %s up to version %d successfully!\n", moduleName, version)
+	return nil
+}
+
+// withScratchDatabase creates an empty database next to the configured one, passes a connection to fn and drops it afterwards
+func withScratchDatabase(db *sql.DB, fn func(scratch *sql.DB) error) error {
+	name := fmt.Sprintf("%s_scratch_%d", cfg.DBName, time.Now().UnixNano())
+	if _, err := db.Exec("CREATE DATABASE `" + name + "`"); err != nil {
+		return fmt.Errorf("failed to create scratch database: %v", err)
+	}
+	defer func() {
+		if _, err := db.Exec("DROP DATABASE `" + name + "`"); err != nil {
+			log.Printf("Failed to drop scratch database %s: %v", name, err)
+		}
+	}()
+
+	scratchCfg := cfg
+	scratchCfg.DBName = name
+	scratch, err := config.SQLStorage(scratchCfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to scratch database: %v", err)
+	}
+	defer scratch.Close()
+
+	return fn(scratch)
+}
+
+// applyMigrations applies migrations for a specific module or all modules, in each tenant schema when tenants are given
+func applyMigrations(db *sql.DB, moduleName string, tenants []string) error {
+	if moduleName != "" {
+		// Run migrations for a specific module
+		if err := migrations.RunForModule(db, moduleName, "up", tenants...); err != nil {
+			return fmt.Errorf("failed to apply migrations for module %s: %v", moduleName, err)
+		}
+	} else {
+		// Run migrations for all modules
+		if err := migrations.RunAll(db, tenants...); err != nil {
+			return fmt.Errorf("failed to apply migrations (up): %v", err)
+		}
+	}
+	fmt.Println("Migrations applied successfully!")
+	return nil
+}
+
+// contractMigrations applies the held contract migrations of a specific module or all modules,
+// in each tenant schema when tenants are given
+func contractMigrations(db *sql.DB, moduleName string, tenants []string) error {
+	if moduleName != "" {
+		if err := migrations.ContractForModule(db, moduleName, tenants...); err != nil {
+			return err
+		}
+	} else {
+		if err := migrations.ContractAll(db, tenants...); err != nil {
+			return err
+		}
+	}
+	fmt.Println("Contract migrations applied successfully!")
+	return nil
+}
+
+// rollbackMigrations rolls back migrations for a specific module or all modules, in each tenant schema when tenants are given
+func rollbackMigrations(db *sql.DB, moduleName string, tenants []string) error {
+	if moduleName != "" {
+		// Rollback migrations for a specific module
+		if err := migrations.RunForModule(db, moduleName, "down", tenants...); err != nil {
+			return fmt.Errorf("failed to rollback migrations for module %s: %v", moduleName, err)
+		}
+	} else {
+		// Rollback migrations for all modules
+		if err := migrations.RollbackAll(db, tenants...); err != nil {
+			return fmt.Errorf("failed to rollback migrations (down): %v", err)
+		}
+	}
+	fmt.Println("Migrations rolled back successfully!")
+	return nil
+}
+
+// adoptsLegacyVersions reports whether a migration command first carries a legacy schema_migrations
+// table over to the per-module version tables. Only the commands that change versions do; reports
+// and dry runs leave the database as it is.
+func adoptsLegacyVersions(command string, dryRun bool) bool {
+	switch command {
+	case "up", "down", "steps", "goto", "force", "contract":
+		return !dryRun
+	}
+	return false
+}
+
+// dumpsSchema reports whether a migration command regenerates the schema snapshots: only the commands
+// that change the schema do, and only with --dump-schema, so a deploy never rewrites tracked files.
+func dumpsSchema(command string, dumpSchema bool) bool {
+	switch command {
+	case "up", "contract", "down", "steps", "goto":
+		return dumpSchema
+	}
+	return false
+}
+
+// handleSchema processes schema snapshot commands and reports whether the snapshots match the database
+func handleSchema(db *sql.DB, command, moduleName, format string) (bool, error) {
+	switch command {
+	case "dump":
+		paths, err := dumpSchemas(db, moduleName)
+		if err == nil && len(paths) == 0 {
+			fmt.Println("No module source directories found; no snapshots written.")
+		}
+		return true, err
+	case "diff":
+		return printSchemaDiff(db, moduleName, format)
+	default:
+		return false, fmt.Errorf("invalid schema command: %s", command)
+	}
+}
+
+// dumpSchemas regenerates the schema snapshots of a specific module or all modules
+func dumpSchemas(db *sql.DB, moduleName string) ([]string, error) {
+	paths, err := migrations.DumpSchemas(db, moduleName)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		fmt.Printf("Schema snapshot written: %s\n", path)
+	}
+	return paths, nil
+}
+
+// printSchemaDiff prints how the database differs from the schema snapshots
+func printSchemaDiff(db *sql.DB, moduleName, format string) (bool, error) {
+	diffs, err := migrations.DiffSchemas(db, moduleName)
+	if err != nil {
+		return false, err
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if diffs == nil {
+			diffs = []migrations.SchemaDiff{}
+		}
+		if err := encoder.Encode(diffs); err != nil {
+			return false, err
+		}
+	case "table":
+		if len(diffs) == 0 {
+			fmt.Println("The database matches the schema snapshots.")
+			break
+		}
+		for _, diff := range diffs {
+			fmt.Printf("--- %s (snapshot)\n+++ %s (database)\n", diff.Snapshot, diff.Module)
+			for _, line := range diff.Changes {
+				fmt.Println(line)
+			}
+		}
+	default:
+		return false, fmt.Errorf("invalid output format: %s", format)
+	}
+
+	return len(diffs) == 0, nil
+}
+
+// printMigrationPlan prints the migrations a command would run without touching the database
+func printMigrationPlan(db *sql.DB, args []string, moduleName, format string) error {
+	op, err := parseOperation(args)
+	if err != nil {
+		return err
+	}
+
+	plan, err := migrations.Plan(db, moduleName, op)
+	if err != nil {
+		return err
+	}
+	return writeMigrationPlan(os.Stdout, plan, format)
+}
+
+// writeMigrationPlan renders a migration plan as a table or JSON
+func writeMigrationPlan(w io.Writer, plan []migrations.PlannedMigration, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if plan == nil {
+			plan = []migrations.PlannedMigration{}
+		}
+		return encoder.Encode(plan)
+	case "table":
+		if len(plan) == 0 {
+			fmt.Fprintln(w, "Nothing to migrate.")
+			return nil
+		}
+		for i, step := range plan {
+			fmt.Fprintf(w, "-- [%d/%d] %s %d %s: %s\n", i+1, len(plan), step.Module, step.Version, step.Direction, step.File)
+			if step.File == "" {
+				fmt.Fprintf(w, "-- (no %s file; only the version would change)\n\n", step.Direction)
+				continue
+			}
+			fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(step.SQL))
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid output format: %s", format)
+	}
+}
+
+// lintMigrations prints the problems found in the migration files and reports whether they passed
+func lintMigrations(moduleName, format string) (bool, error) {
+	var issues []migrations.LintIssue
+	var err error
+	if moduleName != "" {
+		issues, err = migrations.LintModule(moduleName)
+	} else {
+		issues, err = migrations.Lint()
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		if issues == nil {
+			issues = []migrations.LintIssue{}
+		}
+		if err := encoder.Encode(issues); err != nil {
+			return false, err
+		}
+	case "table":
+		if len(issues) == 0 {
+			fmt.Println("No migration problems found.")
+			break
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SEVERITY\tMODULE\tFILE\tMESSAGE")
+		for _, issue := range issues {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", issue.Severity, issue.Module, issue.File, issue.Message)
+		}
+		if err := w.Flush(); err != nil {
+			return false, err
+		}
+	default:
+		return false, fmt.Errorf("invalid output format: %s", format)
+	}
+
+	return !migrations.HasLintErrors(issues), nil
+}
+
+// printMigrationDrift prints applied migrations whose files have changed and reports whether there were none
+func printMigrationDrift(db *sql.DB, moduleName, format string) (bool, error) {
+	var drifted []migrations.DriftedMigration
+	var err error
+	if moduleName != "" {
+		drifted, err = migrations.DriftForModule(db, moduleName)
+	} else {
+		drifted, err = migrations.Drift(db)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if drifted == nil {
+			drifted = []migrations.DriftedMigration{}
+		}
+		if err := encoder.Encode(drifted); err != nil {
+			return false, err
+		}
+	case "table":
+		if len(drifted) == 0 {
+			fmt.Println("No drifted migrations found.")
+			break
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MODULE\tVERSION\tFILE\tSTATUS")
+		for _, entry := range drifted {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", entry.Module, entry.Version, entry.File, entry.Status)
+		}
+		if err := w.Flush(); err != nil {
+			return false, err
+		}
+	default:
+		return false, fmt.Errorf("invalid output format: %s", format)
+	}
+
+	return len(drifted) == 0, nil
+}
+
+// runSeeds loads the seed data of a specific module or all modules for an environment
+func runSeeds(db *sql.DB, env, moduleName, format string) error {
+	var results []seeds.Result
+	var err error
+	if moduleName != "" {
+		results, err = seeds.RunForModule(db, moduleName, env)
+	} else {
+		results, err = seeds.RunAll(db, env)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if results == nil {
+			results = []seeds.Result{}
+		}
+		return encoder.Encode(results)
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MODULE\tSEED\tRESULT")
+		for _, result := range results {
+			status := "unchanged"
+			if result.Applied {
+				status = "applied"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", result.Module, result.Name, status)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("invalid output format: %s", format)
+	}
+}
+
+// printMigrationStatus prints the migration state of one module or all modules
+func printMigrationStatus(db *sql.DB, moduleName, format string) error {
+	var statuses []migrations.ModuleStatus
+	if moduleName != "" {
+		status, err := migrations.StatusForModule(db, moduleName)
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, status)
+	} else {
+		var err error
+		if statuses, err = migrations.Status(db); err != nil {
+			return err
+		}
+	}
+
+	return writeMigrationStatus(os.Stdout, statuses, format)
+}
+
+// writeMigrationStatus renders the migration status of modules as a table or JSON
+func writeMigrationStatus(w io.Writer, statuses []migrations.ModuleStatus, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(statuses)
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MODULE\tVERSION\tDIRTY\tPENDING\tCONTRACT")
+		for _, status := range statuses {
+			version := "none"
+			if status.Version != nil {
+				version = strconv.FormatUint(uint64(*status.Version), 10)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%t\t%d\t%d\n", status.Module, version, status.Dirty, len(status.Pending), len(status.Contract))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			if len(status.Pending) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "\nPending migrations for %s:\n", status.Module)
+			for _, file := range status.Pending {
+				fmt.Fprintf(w, "  - %s\n", file)
+			}
+		}
+
+		for _, status := range statuses {
+			if len(status.Contract) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "\nOutstanding contract migrations for %s (run migrate contract once every instance is upgraded):\n", status.Module)
+			for _, file := range status.Contract {
+				fmt.Fprintf(w, "  - %s\n", file)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid output format: %s", format)
+	}
+}
+
+// printMigrationHistory prints the most recent migration steps of one module or all modules
+func printMigrationHistory(db *sql.DB, moduleName string, limit int, format string) error {
+	entries, err := migrations.History(db, moduleName, limit)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if entries == nil {
+			entries = []migrations.HistoryEntry{}
+		}
+		return encoder.Encode(entries)
+	case "table":
+		if len(entries) == 0 {
+			fmt.Println("No migrations have been run yet.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RAN AT\tMODULE\tVERSION\tDIRECTION\tDURATION\tHOST\tUSER\tRESULT")
+		for _, entry := range entries {
+			result := "ok"
+			if entry.Error != "" {
+				result = "failed: " + entry.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", entry.RanAt.Format(time.DateTime), entry.Module, entry.Version,
+				entry.Direction, time.Duration(entry.DurationMs)*time.Millisecond, entry.Host, entry.User, result)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("invalid output format: %s", format)
+	}
+}
+
+// printTenantStatus prints the migration state of one module or all modules in each tenant schema
+func printTenantStatus(tenants []string, moduleName, format string) error {
+	statuses, err := migrations.StatusForTenants(tenants, moduleName)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, status := range statuses {
+		if status.Error != "" {
+			failed = append(failed, status.Tenant)
+		}
+	}
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(statuses); err != nil {
+			return err
+		}
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TENANT\tMODULE\tVERSION\tDIRTY\tPENDING\tCONTRACT")
+		for _, tenant := range statuses {
+			if tenant.Error != "" {
+				fmt.Fprintf(w, "%s\t-\terror: %s\t\t\t\n", tenant.Tenant, tenant.Error)
+				continue
+			}
+			for _, status := range tenant.Modules {
+				version := "none"
+				if status.Version != nil {
+					version = strconv.FormatUint(uint64(*status.Version), 10)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%d\t%d\n", tenant.Tenant, status.Module, version, status.Dirty, len(status.Pending), len(status.Contract))
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid output format: %s", format)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("status unavailable for %d tenant(s): %s", len(failed), strings.Join(failed, ","))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"auto_verse/app"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// routesCommand lists the HTTP routes registered by every module, without starting the server
+func routesCommand(args []string) error {
+	fs := newFlagSet("routes")
+	format := fs.String("format", "table", "Output format (table or json)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageErrorf("routes", "routes takes no arguments")
+	}
+
+	routes := app.Routes()
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if routes == nil {
+			routes = []app.Route{}
+		}
+		return encoder.Encode(routes)
+	case "table":
+		if len(routes) == 0 {
+			fmt.Println("No routes registered.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "METHOD\tPATH\tMODULE")
+		for _, route := range routes {
+			method := route.Method
+			if method == "" {
+				method = "ANY"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", method, route.Pattern, route.Module)
+		}
+		return w.Flush()
+	default:
+		return usageErrorf("routes", "invalid output format: %s", *format)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"auto_verse/app"
+	"auto_verse/config"
+	"auto_verse/migrations"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// serveCommand starts the application, optionally applying pending migrations first
+func serveCommand(args []string) error {
+	fs := newFlagSet("serve")
+	migrate := fs.Bool("migrate", false, "Apply the pending up migrations of every module before starting")
+	fromDisk := fs.Bool("migrations-from-disk", false, "Read migrations from Modules/<name>/migrations instead of the files embedded in the binary")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		return usageErrorf("serve", "serve takes no arguments")
+	}
+
+	db, err := connectToDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *migrate {
+		migrations.UseDiskSource(*fromDisk)
+		if err := migrations.SetDialect(config.Envs.DBDialect); err != nil {
+			return fmt.Errorf("invalid DB_DIALECT: %v", err)
+		}
+		// Ctrl+C during the migrations stops them; the server handles it itself afterwards
+		ctx, stopInterrupts := signal.NotifyContext(context.Background(), os.Interrupt)
+		migrations.UseContext(ctx)
+		err := migrations.AdoptLegacyVersions(db)
+		if err == nil {
+			err = applyMigrations(db, "", nil)
+		}
+		stopInterrupts()
+		if err != nil {
+			return err
+		}
+	}
+
+	return startApplication(db)
+}
+
+// startApplication boots every registered module and starts the HTTP server
+func startApplication(db *sql.DB) error {
+	fmt.Println("Starting the application...")
+
+	if err := app.Boot(db); err != nil {
+		return fmt.Errorf("failed to boot modules: %v", err)
+	}
+
+	// Create a new ServeMux for routing
+	router := http.NewServeMux()
+
+	// Mount the routes of every registered module under /api/v1
+	app.MountRoutes(router)
+
+	// Default route
+	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Welcome to AutoVerse!")
+	})
+
+	port := ":8080"
+	server := &http.Server{Addr: port, Handler: router}
+
+	// Shut the server and the modules down gracefully on SIGINT/SIGTERM
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("Failed to shut down server: %v", err)
+		}
+	}()
+
+	fmt.Printf("Server is running on http://localhost%s\n", port)
+	serveErr := server.ListenAndServe()
+	if errors.Is(serveErr, http.ErrServerClosed) {
+		serveErr = nil
+	}
+
+	if err := app.Shutdown(); err != nil {
+		log.Printf("Module shutdown error: %v", err)
+	}
+	if serveErr != nil {
+		return fmt.Errorf("failed to start server: %v", serveErr)
+	}
+	fmt.Println("Application stopped.")
+	return nil
+}
@@ -14,6 +14,12 @@ type Config struct {
 	DBPassword             string
 	DBAddress              string
 	DBName                 string
+	DBDialect              string
+	Tenants                string // Comma separated tenant schemas
+	TenantsTable           string // Table listing tenant schemas in a schema_name column
+	TenantConcurrency      int64  // Tenant schemas migrated at once
+	MigrationTimeout       int64  // Seconds a single SQL migration may run, 0 for no limit
+	MigrationLockTimeout   int64  // Seconds to wait for the migration lock and table locks, 0 for the defaults
 	JWTSecret              string
 	JWTExpirationInSeconds int64
 }
@@ -31,6 +37,12 @@ func LoadConfig() Config {
 		DBPassword:             getEnv("DB_PASSWORD", ""),
 		DBAddress:              getEnv("DB_HOST", "localhost"),
 		DBName:                 getEnv("DB_NAME", "ecom"),
+		DBDialect:              getEnv("DB_DIALECT", "mysql"),
+		Tenants:                getEnv("TENANTS", ""),
+		TenantsTable:           getEnv("TENANTS_TABLE", ""),
+		TenantConcurrency:      getEnvAsInt("TENANT_CONCURRENCY", 4),
+		MigrationTimeout:       getEnvAsInt("MIGRATION_STATEMENT_TIMEOUT", 0),
+		MigrationLockTimeout:   getEnvAsInt("MIGRATION_LOCK_TIMEOUT", 0),
 		JWTSecret:              getEnv("JWT_SECRET", "not-so-secret-now-is-it?"),
 		JWTExpirationInSeconds: getEnvAsInt("JWT_EXPIRATION_IN_SECONDS", 3600*24*7),
 	}
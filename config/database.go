@@ -6,6 +6,20 @@ import (
 	//"log"
 )
 
+// MySQLConfig returns the connection settings for the configured database
+func MySQLConfig() mysql.Config {
+	return mysql.Config{
+		User:                 Envs.DBUser,
+		Passwd:               Envs.DBPassword,
+		Addr:                 Envs.DBAddress,
+		DBName:               Envs.DBName,
+		Net:                  "tcp",
+		AllowNativePasswords: true,
+		ParseTime:            true,
+		MultiStatements:      true, // Migration and seed files may hold several statements
+	}
+}
+
 // NewMySQLStorage creates a new MySQL connection
 func SQLStorage(cfg mysql.Config) (*sql.DB, error) {
 	db, err := sql.Open("mysql", cfg.FormatDSN())
@@ -16,7 +30,3 @@ func SQLStorage(cfg mysql.Config) (*sql.DB, error) {
 
 	return db, nil
 }
-
-
-
-
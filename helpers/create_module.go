@@ -1,25 +1,36 @@
-package main
+package helpers
 
 import (
+	"auto_verse/schema"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"text/template"
+	"time"
 )
 
 // ModuleTemplate represents the data needed to generate a module
 type ModuleTemplate struct {
-	ModuleName string
+	ModuleName  string
+	Timestamp   string
+	CreateTable string // Initial CREATE TABLE in the configured dialect
+	DropTable   string // Statements undoing CreateTable
 }
 
-func main() {
-	// Get the module name from the command line
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run helpers/create_module.go <module_name>")
-		return
+// moduleNamePattern matches the module names accepted by CreateModule, which double as Go package names
+var moduleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// CreateModule generates a module under Modules/, with an initial migration written for the dialect,
+// and imports it from Modules/modules.go. It returns the files created.
+func CreateModule(moduleName string, dialect schema.Dialect) ([]string, error) {
+	if !moduleNamePattern.MatchString(moduleName) {
+		return nil, fmt.Errorf("invalid module name %q; use lowercase letters, digits and underscores", moduleName)
+	}
+	if _, err := os.Stat(filepath.Join("Modules", moduleName)); err == nil {
+		return nil, fmt.Errorf("module %s already exists", moduleName)
 	}
-	moduleName := os.Args[1]
 
 	// Define the module structure
 	moduleDir := filepath.Join("Modules", moduleName)
@@ -37,15 +48,18 @@ func main() {
 	// Create directories
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-			fmt.Printf("Failed to create directory %s: %v\n", dir, err)
-			return
+			return nil, fmt.Errorf("failed to create directory %s: %v", dir, err)
 		}
 	}
 
 	// Define template data
 	data := ModuleTemplate{
-		ModuleName: moduleName,
+		ModuleName:  moduleName,
+		Timestamp:   time.Now().Format("20060102150405"), // YYYYMMDDHHMMSS, like make create-migration
+		CreateTable: dialect.CreateTable(moduleName),
+		DropTable:   dialect.DropTable(moduleName),
 	}
+	migrationName := fmt.Sprintf("%s_create_%s_table", data.Timestamp, moduleName)
 
 	// Create files from templates
 	files := []struct {
@@ -59,19 +73,49 @@ func main() {
 		{filepath.Join(moduleDir, "utils", "utils.go"), utilsTemplate},
 		{filepath.Join(moduleDir, "config", "config.go"), configTemplate},
 		{filepath.Join(moduleDir, "tests", "controller_test.go"), testTemplate},
-		{filepath.Join(moduleDir, "migrations", "0001_initial_migration.sql"), migrationTemplate}, // Add migration file
-		{filepath.Join(moduleDir, "migrate.go"), migrateTemplate},                                 // Add migrate.go file
+		{filepath.Join(moduleDir, "migrations", migrationName+".up.sql"), migrationTemplate},       // Add initial migration
+		{filepath.Join(moduleDir, "migrations", migrationName+".down.sql"), downMigrationTemplate}, // and its rollback
+		{filepath.Join(moduleDir, "module.go"), moduleTemplate},                                    // Register the module with the application
 		{filepath.Join(moduleDir, "README.md"), readmeTemplate},
 	}
 
+	paths := make([]string, 0, len(files))
 	for _, file := range files {
 		if err := createFileFromTemplate(file.path, file.template, data); err != nil {
-			fmt.Printf("Failed to create file %s: %v\n", file.path, err)
-			return
+			return paths, fmt.Errorf("failed to create file %s: %v", file.path, err)
 		}
+		paths = append(paths, file.path)
 	}
 
-	fmt.Printf("Module '%s' created successfully!\n", moduleName)
+	// Import the new module so it registers itself with the application
+	if err := registerModuleImport(moduleName); err != nil {
+		return paths, fmt.Errorf("failed to register module %s: %v", moduleName, err)
+	}
+	return paths, nil
+}
+
+// registerModuleImport adds a blank import for the module to Modules/modules.go
+func registerModuleImport(moduleName string) error {
+	path := filepath.Join("Modules", "modules.go")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	importLine := fmt.Sprintf("\t_ \"auto_verse/Modules/%s\"\n", moduleName)
+	if strings.Contains(string(content), importLine) {
+		return nil
+	}
+
+	// Append the import as the last entry of the import block
+	source := string(content)
+	end := strings.LastIndex(source, ")")
+	if end == -1 {
+		return fmt.Errorf("no import block found in %s", path)
+	}
+	source = source[:end] + importLine + source[end:]
+
+	return os.WriteFile(path, []byte(source), 0644)
 }
 
 // createFileFromTemplate creates a file from a template
@@ -125,15 +169,15 @@ type {{.ModuleName | Title}} struct {
 	routesTemplate = `package routes
 
 import (
-	"net/http"
 	"auto_verse/Modules/{{.ModuleName}}/controllers"
 	"auto_verse/Modules/{{.ModuleName}}/middleware"
+	"auto_verse/app"
 )
 
-// Setup{{.ModuleName | Title}}Routes configures routes for the {{.ModuleName}} module
-func Setup{{.ModuleName | Title}}Routes() {
+// Setup{{.ModuleName | Title}}Routes configures routes for the {{.ModuleName}} module on the /api/v1 router
+func Setup{{.ModuleName | Title}}Routes(router *app.Router) {
 	controller := controllers.New{{.ModuleName | Title}}Controller()
-	http.HandleFunc("/{{.ModuleName}}", middleware.LogRequest(controller.GetHandler))
+	router.HandleFunc("/{{.ModuleName}}", middleware.LogRequest(controller.GetHandler))
 }
 `
 
@@ -199,30 +243,70 @@ func Test{{.ModuleName | Title}}Controller_GetHandler(t *testing.T) {
 }
 `
 
-	migrationTemplate = `-- 0001_initial_migration.sql
+	migrationTemplate = `-- {{.Timestamp}}_create_{{.ModuleName}}_table.up.sql
 -- Add your SQL statements here
-CREATE TABLE IF NOT EXISTS {{.ModuleName}} (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-);
+{{.CreateTable}}
+`
+
+	downMigrationTemplate = `-- {{.Timestamp}}_create_{{.ModuleName}}_table.down.sql
+{{.DropTable}}
 `
 
-	migrateTemplate = `package {{.ModuleName}}
+	moduleTemplate = `package {{.ModuleName}}
 
 import (
+	"auto_verse/Modules/{{.ModuleName}}/routes"
+	"auto_verse/app"
 	"database/sql"
-	"auto_verse/migrations"
+	"embed"
+	"io/fs"
+	"path/filepath"
 )
 
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
 func init() {
-	// Register the {{.ModuleName}} migration function
-	migrations.Register(Migrate)
+	// Register the {{.ModuleName}} module with the application
+	app.Register(Module{})
+}
+
+// Module wires the {{.ModuleName}} module into the application
+type Module struct{}
+
+// Name returns the module name
+func (Module) Name() string {
+	return "{{.ModuleName}}"
+}
+
+// DependsOn returns the modules that must be booted and migrated before {{.ModuleName}}
+func (Module) DependsOn() []string {
+	return nil
+}
+
+// RegisterRoutes mounts the {{.ModuleName}} routes on the API router
+func (Module) RegisterRoutes(router *app.Router) {
+	routes.Setup{{.ModuleName | Title}}Routes(router)
+}
+
+// Migrations returns the {{.ModuleName}} migrations embedded in the binary
+func (Module) Migrations() fs.FS {
+	fsys, _ := fs.Sub(migrationFiles, "migrations")
+	return fsys
+}
+
+// MigrationsDir returns the on-disk directory holding the {{.ModuleName}} migrations
+func (Module) MigrationsDir() string {
+	return filepath.Join("Modules", "{{.ModuleName}}", "migrations")
+}
+
+// Init prepares the {{.ModuleName}} module once the database is available
+func (Module) Init(db *sql.DB) error {
+	return nil
 }
 
-// Migrate runs all migrations for the {{.ModuleName}} module
-func Migrate(db *sql.DB) error {
-	// Migration logic for the {{.ModuleName}} module
+// Shutdown releases resources held by the {{.ModuleName}} module
+func (Module) Shutdown() error {
 	return nil
 }
 `
@@ -240,12 +324,13 @@ This module handles {{.ModuleName}}-related functionality.
 - **config/**: Module-specific configuration.
 - **tests/**: Unit and integration tests.
 - **migrations/**: Database migration files.
+- **module.go**: Registers the module with the application.
 
 ## Usage
-- To use the module, import it in your application and call the setup function in your main file.
+- The module registers itself in ` + "`module.go`" + ` and is imported from ` + "`Modules/modules.go`" + `; its routes are mounted under ` + "`/api/v1`" + ` automatically.
 
 ## Migrations
-- Run migrations using the ` + "`Migrate`" + ` function in ` + "`migrate.go`" + `.
+- SQL migrations live in ` + "`migrations/`" + `; Go migrations (e.g. data backfills) and migration hooks are registered from an ` + "`init`" + ` function in the module package and run interleaved with the SQL files by version (see the Go Migrations section of the main README).
 
 ## Testing
 - Run ` + "`go test ./Modules/{{.ModuleName}}/tests`" + ` to run tests for this module.
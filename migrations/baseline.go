@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"auto_verse/schema"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Baseline records a module as already migrated to version without running any SQL, for databases
+// whose tables were created before the module's migrations existed. It checks that every table the
+// migrations up to version would create is present, refuses modules that already track a version,
+// and records checksums for the covered migrations so later edits to them are caught as drift.
+func Baseline(db *sql.DB, moduleName string, version uint) error {
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return err
+	}
+	return baselineModule(db, module, version)
+}
+
+// baselineModule records module as migrated to version once its tables are verified
+func baselineModule(db *sql.DB, module app.Module, version uint) error {
+	current, _, err := readVersion(db, VersionTable(module.Name()))
+	if err != nil {
+		return fmt.Errorf("failed to read version for module %s: %v", module.Name(), err)
+	}
+	if current != nil {
+		return fmt.Errorf("module %s already tracks version %d; use goto or force instead", module.Name(), *current)
+	}
+
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+	var covered []*source.Migration
+	found := false
+	for _, file := range files {
+		if file.Version <= version {
+			covered = append(covered, file)
+			found = found || file.Version == version
+		}
+	}
+	if !found {
+		return fmt.Errorf("module %s has no migration with version %d", module.Name(), version)
+	}
+
+	tables, err := moduleTables(module, &version)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, table := range tables {
+		exists, err := schema.TableExists(db, table)
+		if err != nil {
+			return fmt.Errorf("failed to look up table %s: %v", table, err)
+		}
+		if !exists {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("cannot baseline module %s at version %d: missing tables %s", module.Name(), version, strings.Join(missing, ", "))
+	}
+
+	err = runOnModule(db, module, func(m *migrate.Migrate) error {
+		return m.Force(int(version))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record version %d for module %s: %v", version, module.Name(), err)
+	}
+
+	if err := ensureChecksumTable(db); err != nil {
+		return err
+	}
+	for _, file := range covered {
+		body, err := readMigration(module, file)
+		if err != nil {
+			return err
+		}
+		if err := recordChecksum(db, module.Name(), file.Version, file.Raw, []byte(body)); err != nil {
+			return fmt.Errorf("failed to record checksum of %s: %v", file.Raw, err)
+		}
+	}
+
+	log.Printf("Module %s baselined at version %d (%d table(s) verified)", module.Name(), version, len(tables))
+	return nil
+}
@@ -0,0 +1,75 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var baselineModuleFiles = fstest.MapFS{
+	"1_create_posts.up.sql":   {Data: []byte("CREATE TABLE posts (id INT PRIMARY KEY);")},
+	"1_create_posts.down.sql": {Data: []byte("DROP TABLE posts;")},
+	"2_create_tags.up.sql":    {Data: []byte("CREATE TABLE tags (id INT PRIMARY KEY);")},
+	"2_create_tags.down.sql":  {Data: []byte("DROP TABLE tags;")},
+	"3_create_likes.up.sql":   {Data: []byte("CREATE TABLE likes (id INT PRIMARY KEY);")},
+}
+
+func TestBaseline_RefusesMissingTables(t *testing.T) {
+	module := testModule{name: "baseline_missing_test", files: baselineModuleFiles}
+	db, mem := openMemDB(t)
+	if _, err := db.Exec("CREATE TABLE posts (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := baselineModule(db, module, 2)
+	if err == nil || !strings.Contains(err.Error(), "missing tables tags") {
+		t.Fatalf("Expected the baseline to be refused for the missing tags table, got %v", err)
+	}
+	if mem.hasTable(VersionTable(module.name)) || mem.hasTable(ChecksumTable) {
+		t.Error("Expected a refused baseline to record nothing")
+	}
+}
+
+func TestBaseline_RecordsVersionAndChecksums(t *testing.T) {
+	module := testModule{name: "baseline_records_test", files: baselineModuleFiles}
+	db, mem := openMemDB(t)
+	if _, err := db.Exec("CREATE TABLE posts (id INT PRIMARY KEY); CREATE TABLE tags (id INT PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := baselineModule(db, module, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	version, dirty, err := readVersion(db, VersionTable(module.name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formatVersion(version) != "2" || dirty {
+		t.Errorf("Expected the module at version 2, got %s (dirty %t)", formatVersion(version), dirty)
+	}
+	rows := checksumsOf(t, db, module.name)
+	if len(rows) != 2 || rows[0].file != "1_create_posts.up.sql" || rows[1].file != "2_create_tags.up.sql" {
+		t.Fatalf("Expected checksums for the two baselined migrations, got %+v", rows)
+	}
+	if rows[1].checksum != checksum([]byte("CREATE TABLE tags (id INT PRIMARY KEY);")) {
+		t.Errorf("Expected the checksum of 2_create_tags.up.sql to match its file, got %s", rows[1].checksum)
+	}
+	if mem.hasTable("likes") {
+		t.Error("Expected the baseline not to run any migration")
+	}
+
+	// The module now tracks a version, so a second baseline is refused
+	if err := baselineModule(db, module, 2); err == nil || !strings.Contains(err.Error(), "already tracks version 2") {
+		t.Errorf("Expected a second baseline to be refused, got %v", err)
+	}
+}
+
+func TestBaseline_RefusesUnknownVersion(t *testing.T) {
+	module := testModule{name: "baseline_unknown_test", files: baselineModuleFiles}
+	db, _ := openMemDB(t)
+
+	if err := baselineModule(db, module, 4); err == nil || !strings.Contains(err.Error(), "no migration with version 4") {
+		t.Errorf("Expected an unknown version to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// ChecksumTable records the checksum of every applied migration file
+const ChecksumTable = "schema_migration_checksums"
+
+// Drift policies applied before migrating a module whose applied files have changed
+const (
+	DriftRefuse = "refuse"
+	DriftWarn   = "warn"
+)
+
+// driftPolicy decides whether drifted migrations stop the runner or only log a warning
+var driftPolicy = DriftRefuse
+
+// SetDriftPolicy chooses what happens when an applied migration file has changed: refuse or warn
+func SetDriftPolicy(policy string) error {
+	switch policy {
+	case DriftRefuse, DriftWarn:
+		driftPolicy = policy
+		return nil
+	default:
+		return fmt.Errorf("invalid drift policy: %s", policy)
+	}
+}
+
+// DriftedMigration is an applied migration whose file no longer matches what was run
+type DriftedMigration struct {
+	Module   string `json:"module"`
+	Version  uint   `json:"version"`
+	File     string `json:"file"`
+	Status   string `json:"status"` // "changed" or "missing"
+	Recorded string `json:"recorded_checksum"`
+	Current  string `json:"current_checksum"`
+}
+
+// Drift lists the drifted migrations of every registered module
+func Drift(db *sql.DB) ([]DriftedMigration, error) {
+	modules, err := app.Ordered()
+	if err != nil {
+		return nil, fmt.Errorf("failed to order modules: %v", err)
+	}
+
+	var drifted []DriftedMigration
+	for _, module := range modules {
+		moduleDrift, err := driftForModule(db, module)
+		if err != nil {
+			return nil, err
+		}
+		drifted = append(drifted, moduleDrift...)
+	}
+	return drifted, nil
+}
+
+// DriftForModule lists the drifted migrations of a single module
+func DriftForModule(db *sql.DB, moduleName string) ([]DriftedMigration, error) {
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	return driftForModule(db, module)
+}
+
+// checksum returns the hex encoded SHA-256 of a migration body
+func checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// ensureChecksumTable creates the checksum table if it doesn't exist
+func ensureChecksumTable(db *sql.DB) error {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS `" + ChecksumTable + "` (" +
+		"module VARCHAR(64) NOT NULL, " +
+		"version BIGINT NOT NULL, " +
+		"file VARCHAR(255) NOT NULL, " +
+		"checksum CHAR(64) NOT NULL, " +
+		"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+		"PRIMARY KEY (module, version))")
+	return err
+}
+
+// recordChecksum stores the checksum of a migration that was just applied
+func recordChecksum(db *sql.DB, moduleName string, version uint, file string, body []byte) error {
+	_, err := db.Exec("INSERT INTO `"+ChecksumTable+"` (module, version, file, checksum) VALUES (?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE file = VALUES(file), checksum = VALUES(checksum), applied_at = CURRENT_TIMESTAMP",
+		moduleName, version, file, checksum(body))
+	return err
+}
+
+// forgetChecksum removes the checksum of a migration that was just rolled back
+func forgetChecksum(db *sql.DB, moduleName string, version uint) error {
+	_, err := db.Exec("DELETE FROM `"+ChecksumTable+"` WHERE module = ? AND version = ?", moduleName, version)
+	return err
+}
+
+// forgetChecksumsAbove removes the checksums of the migrations after version, which a force to
+// version records as not applied; a version of -1 removes them all
+func forgetChecksumsAbove(db *sql.DB, moduleName string, version int) error {
+	_, err := db.Exec("DELETE FROM `"+ChecksumTable+"` WHERE module = ? AND version > ?", moduleName, version)
+	return err
+}
+
+// driftForModule compares the recorded checksums of a module with its current migration files
+func driftForModule(db *sql.DB, module app.Module) ([]DriftedMigration, error) {
+	var exists int
+	query := "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	if err := db.QueryRow(query, ChecksumTable).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, nil
+	}
+
+	rows, err := db.Query("SELECT version, file, checksum FROM `"+ChecksumTable+"` WHERE module = ? ORDER BY version", module.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums for module %s: %v", module.Name(), err)
+	}
+	defer rows.Close()
+
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+	byVersion := make(map[uint]*source.Migration, len(files))
+	for _, file := range files {
+		byVersion[file.Version] = file
+	}
+	baseline, squashed := baselineVersion(files)
+
+	var drifted []DriftedMigration
+	for rows.Next() {
+		entry := DriftedMigration{Module: module.Name()}
+		if err := rows.Scan(&entry.Version, &entry.File, &entry.Recorded); err != nil {
+			return nil, err
+		}
+
+		// Checksums of migrations squashed into a baseline are replaced when the baseline is adopted
+		if squashed && (entry.Version < baseline || (entry.Version == baseline && entry.File != byVersion[baseline].Raw)) {
+			continue
+		}
+
+		file, ok := byVersion[entry.Version]
+		if !ok {
+			entry.Status = "missing"
+			drifted = append(drifted, entry)
+			continue
+		}
+		body, err := readMigration(module, file)
+		if err != nil {
+			return nil, err
+		}
+		entry.Current = checksum([]byte(body))
+		if entry.Current != entry.Recorded {
+			entry.File = file.Raw
+			entry.Status = "changed"
+			drifted = append(drifted, entry)
+		}
+	}
+	return drifted, rows.Err()
+}
+
+// checkDrift applies the drift policy before migrating a module up
+func checkDrift(db *sql.DB, module app.Module) error {
+	if err := adoptBaseline(db, module); err != nil {
+		return err
+	}
+
+	drifted, err := driftForModule(db, module)
+	if err != nil {
+		return fmt.Errorf("failed to check drift for module %s: %v", module.Name(), err)
+	}
+	if len(drifted) == 0 {
+		return nil
+	}
+
+	files := make([]string, 0, len(drifted))
+	for _, entry := range drifted {
+		files = append(files, fmt.Sprintf("%s (%s)", entry.File, entry.Status))
+	}
+	message := fmt.Sprintf("applied migrations of module %s have changed since they ran: %s", module.Name(), strings.Join(files, ", "))
+
+	if driftPolicy == DriftWarn {
+		log.Printf("Warning: %s", message)
+		return nil
+	}
+	return fmt.Errorf("%s; restore the files or rerun with --on-drift=warn", message)
+}
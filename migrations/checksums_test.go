@@ -0,0 +1,228 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// setVersion creates a module's version table at a version, as golang-migrate does
+func setVersion(t *testing.T, db *sql.DB, module string, version int64) {
+	t.Helper()
+	table := VersionTable(module)
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS `" + table + "` (version bigint not null primary key, dirty boolean not null)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DELETE FROM `" + table + "`"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO `"+table+"` (version, dirty) VALUES (?, ?)", version, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// recordApplied stores a checksum as if the migration had run
+func recordApplied(t *testing.T, db *sql.DB, module string, version uint, file, body string) {
+	t.Helper()
+	if err := ensureChecksumTable(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordChecksum(db, module, version, file, []byte(body)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checksumRow is a row of the checksum table
+type checksumRow struct {
+	version        uint
+	file, checksum string
+}
+
+// checksumsOf reads the recorded checksums of a module
+func checksumsOf(t *testing.T, db *sql.DB, module string) []checksumRow {
+	t.Helper()
+	rows, err := db.Query("SELECT version, file, checksum FROM `"+ChecksumTable+"` WHERE module = ? ORDER BY version", module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var found []checksumRow
+	for rows.Next() {
+		var row checksumRow
+		if err := rows.Scan(&row.version, &row.file, &row.checksum); err != nil {
+			t.Fatal(err)
+		}
+		found = append(found, row)
+	}
+	return found
+}
+
+// withDriftPolicy sets the drift policy for the rest of a test
+func withDriftPolicy(t *testing.T, policy string) {
+	previous := driftPolicy
+	if err := SetDriftPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { driftPolicy = previous })
+}
+
+func TestCheckDrift_EditedAppliedFile(t *testing.T) {
+	module := testModule{name: "drift_edited_test", files: fstest.MapFS{
+		"1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id INT, email TEXT);")},
+		"2_add_name.up.sql":     {Data: []byte("ALTER TABLE users ADD COLUMN name TEXT;")},
+	}}
+	db, _ := openMemDB(t)
+	setVersion(t, db, module.name, 2)
+	recordApplied(t, db, module.name, 1, "1_create_users.up.sql", "CREATE TABLE users (id INT);")
+	recordApplied(t, db, module.name, 2, "2_add_name.up.sql", "ALTER TABLE users ADD COLUMN name TEXT;")
+
+	drifted, err := driftForModule(db, module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifted) != 1 || drifted[0].Version != 1 || drifted[0].Status != "changed" {
+		t.Fatalf("Expected 1_create_users.up.sql to be reported as changed, got %+v", drifted)
+	}
+
+	withDriftPolicy(t, DriftRefuse)
+	if err := checkDrift(db, module); err == nil || !strings.Contains(err.Error(), "--on-drift=warn") {
+		t.Errorf("Expected the refuse policy to stop the runner, got %v", err)
+	}
+	withDriftPolicy(t, DriftWarn)
+	if err := checkDrift(db, module); err != nil {
+		t.Errorf("Expected the warn policy to let the runner continue, got %v", err)
+	}
+}
+
+func TestCheckDrift_MissingFile(t *testing.T) {
+	module := testModule{name: "drift_missing_test", files: fstest.MapFS{
+		"1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id INT);")},
+	}}
+	db, _ := openMemDB(t)
+	setVersion(t, db, module.name, 2)
+	recordApplied(t, db, module.name, 1, "1_create_users.up.sql", "CREATE TABLE users (id INT);")
+	recordApplied(t, db, module.name, 2, "2_add_name.up.sql", "ALTER TABLE users ADD COLUMN name TEXT;")
+
+	drifted, err := driftForModule(db, module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifted) != 1 || drifted[0].File != "2_add_name.up.sql" || drifted[0].Status != "missing" {
+		t.Fatalf("Expected 2_add_name.up.sql to be reported as missing, got %+v", drifted)
+	}
+
+	withDriftPolicy(t, DriftRefuse)
+	if err := checkDrift(db, module); err == nil || !strings.Contains(err.Error(), "2_add_name.up.sql (missing)") {
+		t.Errorf("Expected the refuse policy to name the missing file, got %v", err)
+	}
+}
+
+func TestCheckDrift_AdoptsSquashedBaseline(t *testing.T) {
+	module := testModule{name: "drift_baseline_test", files: fstest.MapFS{
+		"3_baseline.up.sql":  {Data: []byte("CREATE TABLE users (id INT, name TEXT);")},
+		"4_add_email.up.sql": {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+	}}
+	db, _ := openMemDB(t)
+	setVersion(t, db, module.name, 3)
+	recordApplied(t, db, module.name, 1, "1_create_users.up.sql", "CREATE TABLE users (id INT);")
+	recordApplied(t, db, module.name, 2, "2_backfill.up.go", "go")
+	recordApplied(t, db, module.name, 3, "3_add_name.up.sql", "ALTER TABLE users ADD COLUMN name TEXT;")
+
+	withDriftPolicy(t, DriftRefuse)
+	if err := checkDrift(db, module); err != nil {
+		t.Fatalf("Expected the squashed migrations not to count as drift, got %v", err)
+	}
+	checksums := checksumsOf(t, db, module.name)
+	if len(checksums) != 1 {
+		t.Fatalf("Expected only the baseline checksum to remain, got %+v", checksums)
+	}
+	if row := checksums[0]; row.version != 3 || row.file != "3_baseline.up.sql" || row.checksum != checksum([]byte("CREATE TABLE users (id INT, name TEXT);")) {
+		t.Errorf("Expected the baseline to replace the checksum at its version, got %+v", row)
+	}
+
+	// An edited baseline drifts like any other applied file once adopted
+	module.files["3_baseline.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE users (id BIGINT, name TEXT);")}
+	if err := checkDrift(db, module); err == nil {
+		t.Error("Expected an edited baseline to be refused")
+	}
+}
+
+func TestCheckDrift_RefusesDatabaseInsideSquashedRange(t *testing.T) {
+	module := testModule{name: "drift_squashed_range_test", files: fstest.MapFS{
+		"3_baseline.up.sql": {Data: []byte("CREATE TABLE users (id INT, name TEXT);")},
+	}}
+	db, _ := openMemDB(t)
+	setVersion(t, db, module.name, 1)
+	recordApplied(t, db, module.name, 1, "1_create_users.up.sql", "CREATE TABLE users (id INT);")
+
+	withDriftPolicy(t, DriftWarn)
+	if err := checkDrift(db, module); err == nil || !strings.Contains(err.Error(), "inside the range squashed") {
+		t.Errorf("Expected a database between squashed migrations to be refused, got %v", err)
+	}
+	if checksums := checksumsOf(t, db, module.name); len(checksums) != 1 {
+		t.Errorf("Expected the checksums to be left alone, got %+v", checksums)
+	}
+}
+
+func TestDriftForModule_GoMigrationChanged(t *testing.T) {
+	module := testModule{name: "drift_go_test", files: fstest.MapFS{}}
+	migration := GoMigration{Module: module.name, Version: 1, Name: "backfill", Revision: "1",
+		Up: func(ctx context.Context, conn *sql.Conn) error { return nil }}
+	Register(migration)
+	file := &source.Migration{Version: 1, Identifier: "backfill", Direction: source.Up, Raw: goMigrationFile(migration, source.Up)}
+
+	db, _ := openMemDB(t)
+	setVersion(t, db, module.name, 1)
+	recordApplied(t, db, module.name, 1, file.Raw, goMigrationBody(module.name, file))
+	if drifted, err := driftForModule(db, module); err != nil || len(drifted) != 0 {
+		t.Fatalf("Expected an unchanged Go migration not to drift, got %+v, %v", drifted, err)
+	}
+
+	// Editing an applied Go migration comes with a new revision
+	mu.Lock()
+	migration.Revision = "2"
+	goMigrations[module.name][1] = migration
+	mu.Unlock()
+	drifted, err := driftForModule(db, module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifted) != 1 || drifted[0].File != file.Raw || drifted[0].Status != "changed" {
+		t.Errorf("Expected the revised Go migration to be reported as changed, got %+v", drifted)
+	}
+}
+
+func TestForceModule_ForgetsChecksumsAboveVersion(t *testing.T) {
+	module := testModule{name: "force_checksums_test", files: fstest.MapFS{
+		"1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id INT);")},
+		"2_add_name.up.sql":     {Data: []byte("ALTER TABLE users ADD COLUMN name TEXT;")},
+		"3_add_email.up.sql":    {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+	}}
+	db, _ := openMemDB(t)
+	setVersion(t, db, module.name, 3)
+	recordApplied(t, db, module.name, 1, "1_create_users.up.sql", "CREATE TABLE users (id INT);")
+	recordApplied(t, db, module.name, 2, "2_add_name.up.sql", "ALTER TABLE users ADD COLUMN name TEXT;")
+	recordApplied(t, db, module.name, 3, "3_add_email.up.sql", "ALTER TABLE users ADD COLUMN email TEXT;")
+
+	if err := forceModule(db, module, 1); err != nil {
+		t.Fatal(err)
+	}
+	version, dirty, err := readVersion(db, VersionTable(module.name))
+	if err != nil || version == nil || *version != 1 || dirty {
+		t.Fatalf("Expected the module to be forced to a clean version 1, got %s (dirty %t), %v", formatVersion(version), dirty, err)
+	}
+	if checksums := checksumsOf(t, db, module.name); len(checksums) != 1 || checksums[0].version != 1 {
+		t.Errorf("Expected only the checksum of version 1 to remain, got %+v", checksums)
+	}
+
+	// The forgotten migrations are checked against their files when they run again, not flagged as drift
+	module.files["2_add_name.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE users ADD COLUMN full_name TEXT;")}
+	if drifted, err := driftForModule(db, module); err != nil || len(drifted) != 0 {
+		t.Errorf("Expected no drift after forcing below the edited migration, got %+v, %v", drifted, err)
+	}
+}
@@ -1,9 +1,15 @@
 package migrations
 
 import (
+	"auto_verse/app"
+	"auto_verse/schema"
+	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 	"text/template"
 	"time"
 )
@@ -12,17 +18,85 @@ import (
 type MigrationTemplate struct {
 	MigrationName string
 	Timestamp     string
+	Up            string // Generated up statements, or a placeholder to replace
+	Down          string // Statements undoing Up
 }
 
-// CreateMigration creates a new migration file for the specified module
-func CreateMigration(moduleName, migrationDescription string) {
+// MigrationOptions describes what a generated migration does. Create and Table are exclusive;
+// columns, indexes and foreign keys go into the created table or are added to Table.
+type MigrationOptions struct {
+	Create         string   // Table to create
+	Table          string   // Existing table to alter
+	AddColumns     []string // Columns as name:type, e.g. "phone:VARCHAR(15) NULL"
+	AddIndexes     []string // Indexes as comma separated columns, e.g. "first_name,last_name"
+	Unique         bool     // Make the added indexes unique
+	AddForeignKeys []string // Foreign keys as column:table.column[:on_delete], e.g. "user_id:users.id:cascade"
+}
+
+var (
+	// unsafeName matches the characters replaced when sanitising file and table names
+	unsafeName = regexp.MustCompile(`[^a-z0-9]+`)
+	// identifier matches column names accepted without quoting
+	identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// createDescription recognises descriptions such as create_orders_table
+	createDescription = regexp.MustCompile(`^create_([a-z0-9_]+?)(?:_table)?$`)
+)
+
+// foreignKeyActions are the ON DELETE actions accepted by --add-fk
+var foreignKeyActions = map[string]string{
+	"cascade":     "CASCADE",
+	"set_null":    "SET NULL",
+	"restrict":    "RESTRICT",
+	"no_action":   "NO ACTION",
+	"set_default": "SET DEFAULT",
+}
+
+// placeholderStatement fills a migration whose SQL the description doesn't tell, so the new files
+// pass lint's empty migration check; lint warns until it is replaced
+const placeholderStatement = placeholderMarker + "\nSELECT 1;"
+
+// placeholderMarker is the comment lint looks for to find placeholders left in migrations
+const placeholderMarker = "-- TODO: replace this placeholder with the migration"
+
+// dialect is the database the migrations are written for: generated migrations use it, lint checks
+// against it, and the runner, which only drives MySQL, refuses the others
+var dialect = schema.Dialects["mysql"]
+
+// SetDialect chooses the database dialect migrations are written for: mysql, postgres or sqlite
+func SetDialect(name string) error {
+	d, err := schema.LookupDialect(name)
+	if err != nil {
+		return err
+	}
+	dialect = d
+	return nil
+}
+
+// SanitizeName turns a description or table name into lower snake_case that is safe in file
+// names and unquoted SQL, e.g. "Add Phone-Index!" becomes add_phone_index
+func SanitizeName(name string) string {
+	name = strings.Trim(unsafeName.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if len(name) > 64 {
+		name = strings.TrimRight(name[:64], "_")
+	}
+	return name
+}
+
+// CreateMigration creates new up and down migration files for the specified module and returns their paths.
+// Without options, a description such as create_orders_table generates that table and anything else
+// generates empty files to fill in.
+func CreateMigration(moduleName, migrationDescription string, opts MigrationOptions) ([]string, error) {
+	up, down, err := migrationStatements(&migrationDescription, opts)
+	if err != nil {
+		return nil, err
+	}
+
 	// Define the migration directory
 	migrationDir := filepath.Join("Modules", moduleName, "migrations")
 
 	// Create the migrations directory if it doesn't exist
 	if err := os.MkdirAll(migrationDir, os.ModePerm); err != nil {
-		fmt.Printf("Failed to create migrations directory: %v\n", err)
-		return
+		return nil, fmt.Errorf("failed to create migrations directory: %v", err)
 	}
 
 	// Generate a timestamp for the migration file name
@@ -39,21 +113,199 @@ func CreateMigration(moduleName, migrationDescription string) {
 	data := MigrationTemplate{
 		MigrationName: migrationDescription,
 		Timestamp:     timestamp,
+		Up:            strings.Join(up, "\n\n"),
+		Down:          strings.Join(down, "\n\n"),
 	}
 
 	// Create the up migration file from the template
 	if err := createFileFromTemplate(upFilePath, upMigrationTemplate, data); err != nil {
-		fmt.Printf("Failed to create up migration file: %v\n", err)
-		return
+		return nil, fmt.Errorf("failed to create up migration file: %v", err)
 	}
 
 	// Create the down migration file from the template
 	if err := createFileFromTemplate(downFilePath, downMigrationTemplate, data); err != nil {
-		fmt.Printf("Failed to create down migration file: %v\n", err)
-		return
+		return nil, fmt.Errorf("failed to create down migration file: %v", err)
+	}
+
+	return []string{upFilePath, downFilePath}, nil
+}
+
+// migrationStatements builds the up and down statements for the options, sanitising the
+// description and deriving one from the options when it is empty
+func migrationStatements(description *string, opts MigrationOptions) ([]string, []string, error) {
+	opts.Create = SanitizeName(opts.Create)
+	opts.Table = SanitizeName(opts.Table)
+	*description = SanitizeName(*description)
+
+	altering := len(opts.AddColumns) > 0 || len(opts.AddIndexes) > 0 || len(opts.AddForeignKeys) > 0
+	switch {
+	case opts.Create != "" && opts.Table != "":
+		return nil, nil, fmt.Errorf("use either --create or --table, not both")
+	case opts.Create == "" && opts.Table == "" && altering:
+		return nil, nil, fmt.Errorf("--add-column, --add-index and --add-fk need --create or --table")
+	case opts.Table != "" && !altering:
+		return nil, nil, fmt.Errorf("--table needs at least one --add-column, --add-index or --add-fk")
+	case opts.Create == "" && opts.Table == "":
+		if *description == "" {
+			return nil, nil, fmt.Errorf("a migration description is required")
+		}
+		if match := createDescription.FindStringSubmatch(*description); match != nil {
+			opts.Create = match[1]
+		} else {
+			return []string{placeholderStatement}, []string{placeholderStatement}, nil
+		}
+	}
+
+	table := opts.Create + opts.Table
+	if *description == "" {
+		if opts.Create != "" {
+			*description = SanitizeName("create_" + table + "_table")
+		} else {
+			*description = SanitizeName("alter_" + table + "_table")
+		}
+	}
+
+	var columns, columnNames []string
+	for _, spec := range opts.AddColumns {
+		name, definition, ok := strings.Cut(spec, ":")
+		if !ok || strings.TrimSpace(definition) == "" || !identifier.MatchString(name) {
+			return nil, nil, fmt.Errorf("invalid column %q; use name:type", spec)
+		}
+		columns = append(columns, name+" "+strings.TrimSpace(definition))
+		columnNames = append(columnNames, name)
+	}
+
+	type index struct {
+		name    string
+		columns []string
+	}
+	var indexes []index
+	for _, spec := range opts.AddIndexes {
+		cols := strings.Split(spec, ",")
+		for i, col := range cols {
+			cols[i] = strings.TrimSpace(col)
+			if !identifier.MatchString(cols[i]) {
+				return nil, nil, fmt.Errorf("invalid index %q; use column[,column...]", spec)
+			}
+		}
+		prefix := "idx"
+		if opts.Unique {
+			prefix = "uq"
+		}
+		indexes = append(indexes, index{name: SanitizeName(prefix + "_" + table + "_" + strings.Join(cols, "_")), columns: cols})
+	}
+
+	var keys []schema.ForeignKey
+	for _, spec := range opts.AddForeignKeys {
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, nil, fmt.Errorf("invalid foreign key %q; use column:table.column[:on_delete]", spec)
+		}
+		refTable, refColumn, ok := strings.Cut(parts[1], ".")
+		if !ok || !identifier.MatchString(parts[0]) || !identifier.MatchString(refTable) || !identifier.MatchString(refColumn) {
+			return nil, nil, fmt.Errorf("invalid foreign key %q; use column:table.column[:on_delete]", spec)
+		}
+		key := schema.ForeignKey{Name: SanitizeName("fk_" + table + "_" + parts[0]), Column: parts[0], RefTable: refTable, RefColumn: refColumn}
+		if len(parts) == 3 {
+			action, ok := foreignKeyActions[SanitizeName(parts[2])]
+			if !ok {
+				return nil, nil, fmt.Errorf("invalid ON DELETE action %q in foreign key %q", parts[2], spec)
+			}
+			key.OnDelete = action
+		}
+		keys = append(keys, key)
+	}
+
+	var up, down []string
+	if opts.Create != "" {
+		definitions := columns
+		for _, key := range keys {
+			if !slices.Contains(columnNames, key.Column) {
+				return nil, nil, fmt.Errorf("foreign key column %s is not a column of %s; add it with --add-column", key.Column, table)
+			}
+			definitions = append(definitions, dialect.ForeignKey(key))
+		}
+		up = append(up, dialect.CreateTable(table, definitions...))
+		for _, index := range indexes {
+			up = append(up, dialect.CreateIndex(index.name, table, index.columns, opts.Unique))
+		}
+		return up, []string{dialect.DropTable(table)}, nil
 	}
 
-	fmt.Printf("Migration files created:\n- %s\n- %s\n", upFilePath, downFilePath)
+	for i, column := range columns {
+		up = append(up, dialect.AddColumn(table, column))
+		down = append(down, dialect.DropColumn(table, columnNames[i]))
+	}
+	for _, index := range indexes {
+		up = append(up, dialect.CreateIndex(index.name, table, index.columns, opts.Unique))
+		down = append(down, dialect.DropIndex(index.name, table))
+	}
+	for _, key := range keys {
+		statement, err := dialect.AddForeignKey(table, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		up = append(up, statement)
+		down = append(down, dialect.DropForeignKey(key.Name, table))
+	}
+	slices.Reverse(down)
+	return up, down, nil
+}
+
+// CreateMigrationFromModels compares a module's model structs with the database and writes
+// up/down migration files for the difference. It returns no paths when the schema already matches.
+func CreateMigrationFromModels(db *sql.DB, moduleName, migrationDescription string) ([]string, error) {
+	if dialect.Name != "mysql" {
+		return nil, fmt.Errorf("generating migrations from models supports mysql only, not %s", dialect.Name)
+	}
+	if migrationDescription = SanitizeName(migrationDescription); migrationDescription == "" {
+		return nil, fmt.Errorf("a migration description is required")
+	}
+
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := module.(schema.ModelProvider)
+	if !ok {
+		return nil, fmt.Errorf("module %s does not declare any models", moduleName)
+	}
+
+	desired, err := schema.FromModels(provider.Models())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s models: %v", moduleName, err)
+	}
+	names := make([]string, 0, len(desired))
+	for _, table := range desired {
+		names = append(names, table.Name)
+	}
+	current, err := schema.Inspect(db, names)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := schema.Diff(current, desired)
+	if changes.Empty() {
+		return nil, nil
+	}
+
+	migrationDir := filepath.Join("Modules", moduleName, "migrations")
+	if err := os.MkdirAll(migrationDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create migrations directory: %v", err)
+	}
+
+	timestamp := time.Now().Format("20060102150405") // YYYYMMDDHHMMSS format
+	upFilePath := filepath.Join(migrationDir, fmt.Sprintf("%s_%s.up.sql", timestamp, migrationDescription))
+	downFilePath := filepath.Join(migrationDir, fmt.Sprintf("%s_%s.down.sql", timestamp, migrationDescription))
+
+	header := fmt.Sprintf("-- %s_%s.%%s.sql\n-- Generated from the %s models\n\n", timestamp, migrationDescription, moduleName)
+	if err := os.WriteFile(upFilePath, []byte(fmt.Sprintf(header, "up")+strings.Join(changes.Up, "\n\n")+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to create up migration file: %v", err)
+	}
+	if err := os.WriteFile(downFilePath, []byte(fmt.Sprintf(header, "down")+strings.Join(changes.Down, "\n\n")+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to create down migration file: %v", err)
+	}
+	return []string{upFilePath, downFilePath}, nil
 }
 
 // createFileFromTemplate creates a file from a template
@@ -71,19 +323,13 @@ func createFileFromTemplate(path, tmpl string, data MigrationTemplate) error {
 // Template for up migration files
 var upMigrationTemplate = `-- {{.Timestamp}}_{{.MigrationName}}.up.sql
 -- Add your SQL statements here
-
--- Example: Create a table
-CREATE TABLE {{.MigrationName}} (
-    id INT AUTO_INCREMENT PRIMARY KEY,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-);
-`
+{{if .Up}}
+{{.Up}}
+{{end}}`
 
 // Template for down migration files
 var downMigrationTemplate = `-- {{.Timestamp}}_{{.MigrationName}}.down.sql
 -- Add your SQL statements here
-
--- Example: Drop the table
-DROP TABLE IF EXISTS {{.MigrationName}};
-`
+{{if .Down}}
+{{.Down}}
+{{end}}`
@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrationStatements_AddIndexDescriptionDoesNotCreateTable(t *testing.T) {
+	description := "Add phone index!"
+	up, down, err := migrationStatements(&description, MigrationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if description != "add_phone_index" {
+		t.Errorf("Expected description add_phone_index, got %q", description)
+	}
+	if len(up) != 1 || up[0] != placeholderStatement || len(down) != 1 || down[0] != placeholderStatement {
+		t.Errorf("Expected a placeholder migration, got up %q and down %q", up, down)
+	}
+}
+
+func TestCreateFileFromTemplate_PlaceholderMigrationPassesLint(t *testing.T) {
+	description := "backfill_full_name"
+	up, down, err := migrationStatements(&description, MigrationOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := MigrationTemplate{MigrationName: description, Timestamp: "20250101000000", Up: strings.Join(up, "\n\n"), Down: strings.Join(down, "\n\n")}
+
+	dir := t.TempDir()
+	for name, tmpl := range map[string]string{
+		"20250101000000_backfill_full_name.up.sql":   upMigrationTemplate,
+		"20250101000000_backfill_full_name.down.sql": downMigrationTemplate,
+	} {
+		if err := createFileFromTemplate(filepath.Join(dir, name), tmpl, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	issues, _, err := lintFiles("users", os.DirFS(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if HasLintErrors(issues) {
+		t.Errorf("Expected the scaffolded migration to pass lint, got %+v", issues)
+	}
+	if len(issues) != 2 || issues[0].Severity != LintWarning {
+		t.Errorf("Expected a placeholder warning for each file, got %+v", issues)
+	}
+}
+
+func TestMigrationStatements_AlterTable(t *testing.T) {
+	description := ""
+	up, down, err := migrationStatements(&description, MigrationOptions{
+		Table:          "Users",
+		AddColumns:     []string{"phone:VARCHAR(15) NULL", "company_id:CHAR(36) NULL"},
+		AddIndexes:     []string{"phone"},
+		AddForeignKeys: []string{"company_id:companies.id:set_null"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if description != "alter_users_table" {
+		t.Errorf("Expected description alter_users_table, got %q", description)
+	}
+	expectedUp := []string{
+		"ALTER TABLE users ADD COLUMN phone VARCHAR(15) NULL;",
+		"ALTER TABLE users ADD COLUMN company_id CHAR(36) NULL;",
+		"CREATE INDEX idx_users_phone ON users (phone);",
+		"ALTER TABLE users ADD CONSTRAINT fk_users_company_id FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE SET NULL;",
+	}
+	expectedDown := []string{
+		"ALTER TABLE users DROP FOREIGN KEY fk_users_company_id;",
+		"DROP INDEX idx_users_phone ON users;",
+		"ALTER TABLE users DROP COLUMN company_id;",
+		"ALTER TABLE users DROP COLUMN phone;",
+	}
+	if strings.Join(up, "\n") != strings.Join(expectedUp, "\n") {
+		t.Errorf("Unexpected up statements:\n%s", strings.Join(up, "\n"))
+	}
+	if strings.Join(down, "\n") != strings.Join(expectedDown, "\n") {
+		t.Errorf("Unexpected down statements:\n%s", strings.Join(down, "\n"))
+	}
+}
+
+func TestMigrationStatements_RejectsInvalidOptions(t *testing.T) {
+	for name, opts := range map[string]MigrationOptions{
+		"create and table":      {Create: "orders", Table: "users", AddColumns: []string{"total:INT"}},
+		"column without table":  {AddColumns: []string{"total:INT"}},
+		"column without type":   {Table: "users", AddColumns: []string{"total"}},
+		"unknown fk action":     {Table: "users", AddForeignKeys: []string{"role_id:roles.id:explode"}},
+		"fk on missing column":  {Create: "orders", AddForeignKeys: []string{"user_id:users.id"}},
+		"table without changes": {Table: "users"},
+	} {
+		description := "change"
+		if _, _, err := migrationStatements(&description, opts); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// migrationStep identifies the migration golang-migrate is currently running
+type migrationStep struct {
+	version   uint
+	direction source.Direction
+	started   time.Time
+}
+
+// moduleDriver wraps the MySQL driver of a module so that Go migrations run in place of their
+// placeholder body. golang-migrate marks the target version dirty before running each migration,
+// which is how the driver learns which version and direction the next Run call belongs to.
+type moduleDriver struct {
+	database.Driver
+	db      *sql.DB
+	conn    *sql.Conn // connection migrations, guards and post hooks run on
+	module  string
+	source  *memorySource
+	connID  int64          // id of the connection migrations run on, for killing its statement
+	current int            // version last recorded in the version table
+	step    *migrationStep // migration in progress, nil between migrations
+}
+
+// newModuleDriver wraps a database driver for a module
+func newModuleDriver(db *sql.DB, conn *sql.Conn, moduleName string, driver database.Driver, src *memorySource, connID int64) (*moduleDriver, error) {
+	current, _, err := driver.Version()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureChecksumTable(db); err != nil {
+		return nil, fmt.Errorf("failed to create checksum table: %v", err)
+	}
+	if err := ensureHistoryTable(db); err != nil {
+		return nil, fmt.Errorf("failed to create history table: %v", err)
+	}
+	return &moduleDriver{Driver: driver, db: db, conn: conn, module: moduleName, source: src, connID: connID, current: current}, nil
+}
+
+// SetVersion records the version and tracks the migration that is about to run
+func (d *moduleDriver) SetVersion(version int, dirty bool) error {
+	if dirty {
+		// Going up, the target is the migration being applied; going down,
+		// the target is the previous version and the current one is being reverted
+		if version > d.current {
+			d.step = &migrationStep{version: uint(version), direction: source.Up, started: time.Now()}
+		} else {
+			d.step = &migrationStep{version: uint(d.current), direction: source.Down, started: time.Now()}
+		}
+
+		// An interrupted run or a refusing guard stops before the version is marked dirty
+		err := runContext.Err()
+		if err != nil {
+			err = fmt.Errorf("migration run interrupted before version %d (%s): %v", d.step.version, d.step.direction, err)
+		} else {
+			err = runGuard(d.conn, d.module, d.step)
+		}
+		if err != nil {
+			if historyErr := recordHistory(d.db, d.module, d.step, d.file(d.step), err); historyErr != nil {
+				log.Printf("Failed to record history of version %d for module %s: %v", d.step.version, d.module, historyErr)
+			}
+			d.step = nil
+			return err
+		}
+	}
+
+	if err := d.Driver.SetVersion(version, dirty); err != nil {
+		return err
+	}
+
+	d.current = version
+	if !dirty && d.step != nil {
+		step := d.step
+		d.step = nil
+		return d.finish(step)
+	}
+	return nil
+}
+
+// finish records the checksum of an applied migration, or forgets it once rolled back, runs the
+// migration's post hook and appends the step, with any hook failure, to the history
+func (d *moduleDriver) finish(step *migrationStep) error {
+	if err := d.updateChecksum(step); err != nil {
+		return err
+	}
+
+	hookErr := runAfter(d.conn, d.module, step)
+	if err := recordHistory(d.db, d.module, step, d.file(step), hookErr); err != nil {
+		return fmt.Errorf("failed to record history of version %d: %v", step.version, err)
+	}
+	return hookErr
+}
+
+// updateChecksum records the checksum of an applied migration, or forgets it once rolled back
+func (d *moduleDriver) updateChecksum(step *migrationStep) error {
+	if step.direction == source.Down {
+		if err := forgetChecksum(d.db, d.module, step.version); err != nil {
+			return fmt.Errorf("failed to remove checksum of version %d: %v", step.version, err)
+		}
+		return nil
+	}
+
+	m, ok := d.source.migrations.Up(step.version)
+	if !ok {
+		return nil
+	}
+	if err := recordChecksum(d.db, d.module, step.version, m.Raw, d.source.bodies[m.Raw]); err != nil {
+		return fmt.Errorf("failed to record checksum of %s: %v", m.Raw, err)
+	}
+	return nil
+}
+
+// file returns the name of the migration file a step runs, empty when it has none
+func (d *moduleDriver) file(step *migrationStep) string {
+	m, ok := d.source.migrations.Up(step.version)
+	if step.direction == source.Down {
+		m, ok = d.source.migrations.Down(step.version)
+	}
+	if !ok {
+		return ""
+	}
+	return m.Raw
+}
+
+// Run executes a migration body and records failed steps in the history
+func (d *moduleDriver) Run(migration io.Reader) error {
+	err := d.execute(migration)
+	if err != nil && d.step != nil {
+		if historyErr := recordHistory(d.db, d.module, d.step, d.file(d.step), err); historyErr != nil {
+			log.Printf("Failed to record history of version %d for module %s: %v", d.step.version, d.module, historyErr)
+		}
+	}
+	return err
+}
+
+// run executes a migration body, calling the registered Go function for Go migrations with ctx
+// and the migration connection
+func (d *moduleDriver) run(ctx context.Context, migration io.Reader) error {
+	if d.step != nil {
+		if goMigration, ok := goMigration(d.module, d.step.version); ok {
+			// Drain the placeholder body so golang-migrate's buffering goroutine can finish
+			if _, err := io.Copy(io.Discard, migration); err != nil {
+				return err
+			}
+
+			fn := goMigration.Up
+			if d.step.direction == source.Down {
+				fn = goMigration.Down
+			}
+			if err := fn(ctx, d.conn); err != nil {
+				return fmt.Errorf("go migration %d_%s (%s) failed: %v", goMigration.Version, goMigration.Name, d.step.direction, err)
+			}
+			return nil
+		}
+	}
+
+	return d.Driver.Run(migration)
+}
@@ -0,0 +1,123 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// MigrationFunc is a function that runs migrations for a module. It runs on the connection SQL
+// migrations use, so the lock timeout applies to its statements and a timed out or interrupted
+// run kills the statement it is running; ctx is done then too, and Go code between statements
+// must stop when it is.
+type MigrationFunc func(ctx context.Context, conn *sql.Conn) error
+
+// GoMigration is a migration written in Go, for work such as data backfills that SQL files can't express.
+// It is versioned like a SQL file and runs interleaved with the module's SQL migrations in version order.
+type GoMigration struct {
+	Module  string        // Module the migration belongs to
+	Version uint          // Version, usually a YYYYMMDDHHMMSS timestamp like the SQL files
+	Name    string        // Short description, used in logs and plans
+	Up      MigrationFunc // Applies the migration
+	Down    MigrationFunc // Reverts the migration; optional
+	Phase   string        // PhaseExpand (the default) or PhaseContract
+	// Revision identifies the code of the migration; change it when editing a migration that has
+	// already run, so drift checks flag the databases that ran the previous code
+	Revision string
+}
+
+// goMigrations holds the registered Go migrations keyed by module and version
+var goMigrations = make(map[string]map[uint]GoMigration)
+
+// Register adds a Go migration to the registry; call it from the module's init function
+func Register(migration GoMigration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if migration.Module == "" || migration.Name == "" || migration.Up == nil {
+		panic(fmt.Sprintf("go migration %d must have a module, a name and an up function", migration.Version))
+	}
+	if migration.Phase != "" && migration.Phase != PhaseExpand && migration.Phase != PhaseContract {
+		panic(fmt.Sprintf("go migration %d has invalid phase %q", migration.Version, migration.Phase))
+	}
+	if goMigrations[migration.Module] == nil {
+		goMigrations[migration.Module] = make(map[uint]GoMigration)
+	}
+	if _, exists := goMigrations[migration.Module][migration.Version]; exists {
+		panic(fmt.Sprintf("go migration %d registered twice for module %s", migration.Version, migration.Module))
+	}
+	goMigrations[migration.Module][migration.Version] = migration
+}
+
+// goMigration looks up the Go migration of a module for a version
+func goMigration(moduleName string, version uint) (GoMigration, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	migration, ok := goMigrations[moduleName][version]
+	return migration, ok
+}
+
+// goMigrationFile is the name under which a Go migration appears in plans and status output
+func goMigrationFile(migration GoMigration, direction source.Direction) string {
+	return fmt.Sprintf("%d_%s.%s.go", migration.Version, migration.Name, direction)
+}
+
+// goMigrationBody is the placeholder body of a Go migration. Its checksum is recorded like a SQL
+// file's, so renaming the migration, changing its phase or Down function, or bumping its Revision
+// is reported as drift.
+func goMigrationBody(moduleName string, m *source.Migration) string {
+	body := fmt.Sprintf("-- Go migration: %s (%s)\n", m.Identifier, m.Raw)
+	if migration, ok := goMigration(moduleName, m.Version); ok {
+		phase := migration.Phase
+		if phase == "" {
+			phase = PhaseExpand
+		}
+		body += fmt.Sprintf("-- %s phase, reversible: %t, revision: %q\n", phase, migration.Down != nil, migration.Revision)
+	}
+	return body
+}
+
+// isGoMigration reports whether a listed migration is a Go function rather than a SQL file
+func isGoMigration(m *source.Migration) bool {
+	return strings.HasSuffix(m.Raw, ".go")
+}
+
+// moduleMigrations returns the SQL files and Go migrations of a module for one direction, ordered by version
+func moduleMigrations(module app.Module, direction source.Direction) ([]*source.Migration, error) {
+	files, err := listMigrations(moduleFS(module), direction)
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	for _, migration := range goMigrations[module.Name()] {
+		if direction == source.Down && migration.Down == nil {
+			continue
+		}
+		files = append(files, &source.Migration{
+			Version:    migration.Version,
+			Identifier: migration.Name,
+			Direction:  direction,
+			Raw:        goMigrationFile(migration, direction),
+		})
+	}
+	mu.Unlock()
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Version < files[j].Version })
+
+	// Migrations squashed into a baseline no longer run
+	if baseline, ok := baselineVersion(files); ok {
+		for i, file := range files {
+			if file.Version >= baseline {
+				return files[i:], nil
+			}
+		}
+	}
+	return files, nil
+}
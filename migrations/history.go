@@ -0,0 +1,126 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+)
+
+// HistoryTable is an append-only log of every migration step the runner has executed
+const HistoryTable = "schema_migration_history"
+
+// HistoryEntry is one migration step recorded in the history table
+type HistoryEntry struct {
+	ID         int64     `json:"id"`
+	Module     string    `json:"module"`
+	Version    uint      `json:"version"`
+	File       string    `json:"file"`
+	Direction  string    `json:"direction"`
+	DurationMs int64     `json:"duration_ms"`
+	Host       string    `json:"host"`
+	User       string    `json:"user"`
+	Error      string    `json:"error,omitempty"` // empty when the step succeeded
+	RanAt      time.Time `json:"ran_at"`
+}
+
+// History returns the most recent migration steps of a specific module, or of every module
+// when moduleName is empty, newest first. A limit of 0 returns the whole history.
+func History(db *sql.DB, moduleName string, limit int) ([]HistoryEntry, error) {
+	if moduleName != "" {
+		if _, err := app.Get(moduleName); err != nil {
+			return nil, err
+		}
+	}
+
+	var exists int
+	query := "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	if err := db.QueryRow(query, HistoryTable).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to look up migration history: %v", err)
+	}
+	if exists == 0 {
+		return nil, nil
+	}
+
+	query = "SELECT id, module, version, file, direction, duration_ms, host, `user`, error, ran_at FROM `" + HistoryTable + "`"
+	var args []any
+	if moduleName != "" {
+		query += " WHERE module = ?"
+		args = append(args, moduleName)
+	}
+	query += " ORDER BY id DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration history: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var entry HistoryEntry
+		var stepErr sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Module, &entry.Version, &entry.File, &entry.Direction,
+			&entry.DurationMs, &entry.Host, &entry.User, &stepErr, &entry.RanAt); err != nil {
+			return nil, fmt.Errorf("failed to read migration history: %v", err)
+		}
+		entry.Error = stepErr.String
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// ensureHistoryTable creates the history table if it doesn't exist
+func ensureHistoryTable(db *sql.DB) error {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS `" + HistoryTable + "` (" +
+		"id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY, " +
+		"module VARCHAR(64) NOT NULL, " +
+		"version BIGINT NOT NULL, " +
+		"file VARCHAR(255) NOT NULL, " +
+		"direction VARCHAR(4) NOT NULL, " +
+		"duration_ms BIGINT NOT NULL, " +
+		"host VARCHAR(255) NOT NULL, " +
+		"`user` VARCHAR(255) NOT NULL, " +
+		"error TEXT NULL, " +
+		"ran_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+		"INDEX idx_schema_migration_history_module (module, id))")
+	return err
+}
+
+// recordHistory appends a finished migration step to the history table; stepErr is nil on success
+func recordHistory(db *sql.DB, moduleName string, step *migrationStep, file string, stepErr error) error {
+	var message sql.NullString
+	if stepErr != nil {
+		message = sql.NullString{String: stepErr.Error(), Valid: true}
+	}
+	_, err := db.Exec("INSERT INTO `"+HistoryTable+"` (module, version, file, direction, duration_ms, host, `user`, error) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		moduleName, step.version, file, string(step.direction), time.Since(step.started).Milliseconds(), runnerHost(), runnerUser(), message)
+	return err
+}
+
+// runnerHost returns the name of the machine running the migrations
+func runnerHost() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return host
+}
+
+// runnerUser returns the operating system user running the migrations
+func runnerUser() string {
+	if current, err := user.Current(); err == nil && current.Username != "" {
+		return current.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+func TestHistory_RecordsEveryStep(t *testing.T) {
+	module := testModule{name: "history_steps_test", files: fstest.MapFS{
+		"1_create_posts.up.sql":   {Data: []byte("CREATE TABLE posts (id INT PRIMARY KEY);")},
+		"1_create_posts.down.sql": {Data: []byte("DROP TABLE posts;")},
+		"2_create_tags.up.sql":    {Data: []byte("CREATE TABLE tags (id INT PRIMARY KEY);")},
+		"2_create_tags.down.sql":  {Data: []byte("DROP TABLE tags;")},
+		"3_drop_likes.up.sql":     {Data: []byte("DROP TABLE likes;")},
+	}}
+	db, _ := openMemDB(t)
+
+	if err := runOnModule(db, module, func(m *migrate.Migrate) error { return m.Migrate(2) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := runOnModule(db, module, func(m *migrate.Migrate) error { return m.Steps(-1) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := runOnModule(db, module, func(m *migrate.Migrate) error { return m.Up() }); err == nil {
+		t.Fatal("Expected dropping a missing table to fail")
+	}
+
+	entries, err := History(db, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		version   uint
+		file      string
+		direction string
+		failed    bool
+	}{
+		{3, "3_drop_likes.up.sql", "up", true},
+		{2, "2_create_tags.up.sql", "up", false},
+		{2, "2_create_tags.down.sql", "down", false},
+		{2, "2_create_tags.up.sql", "up", false},
+		{1, "1_create_posts.up.sql", "up", false},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d history entries, got %+v", len(expected), entries)
+	}
+	for i, want := range expected {
+		entry := entries[i]
+		if entry.Module != module.name || entry.Version != want.version || entry.File != want.file || entry.Direction != want.direction {
+			t.Errorf("Entry %d: expected %s %d %s (%s), got %s %d %s (%s)", i, module.name, want.version, want.direction, want.file,
+				entry.Module, entry.Version, entry.Direction, entry.File)
+		}
+		if failed := entry.Error != ""; failed != want.failed {
+			t.Errorf("Entry %d: expected failed=%t, got error %q", i, want.failed, entry.Error)
+		}
+	}
+	if !strings.Contains(entries[0].Error, "likes") {
+		t.Errorf("Expected the failed step to keep its error, got %q", entries[0].Error)
+	}
+
+	limited, err := History(db, "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) != 2 || limited[0].ID != entries[0].ID || limited[1].ID != entries[1].ID {
+		t.Errorf("Expected the limit to keep the two newest entries, got %+v", limited)
+	}
+}
+
+func TestHistory_EmptyWithoutTable(t *testing.T) {
+	db, _ := openMemDB(t)
+
+	entries, err := History(db, "", 0)
+	if err != nil || entries != nil {
+		t.Errorf("Expected no history before anything ran, got %+v, %v", entries, err)
+	}
+}
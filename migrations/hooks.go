@@ -0,0 +1,102 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Hook attaches checks and follow-up work to one migration of a module, SQL file or Go migration.
+// Declare hooks from an init function in the module package.
+type Hook struct {
+	Module    string           // Module the migration belongs to
+	Version   uint             // Version of the migration
+	Direction source.Direction // Direction the hook applies to; up when empty
+	Guard     MigrationFunc    // Runs before the migration; an error aborts it before any SQL runs
+	After     MigrationFunc    // Runs after the migration succeeded, e.g. to invalidate caches
+}
+
+// hooks holds the registered hooks keyed by module, then version and direction
+var hooks = make(map[string]map[hookKey]Hook)
+
+// hookKey identifies the migration a hook belongs to within a module
+type hookKey struct {
+	version   uint
+	direction source.Direction
+}
+
+// RegisterHook adds a guard and/or post hook for a migration; call it from the module's init function
+func RegisterHook(hook Hook) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if hook.Direction == "" {
+		hook.Direction = source.Up
+	}
+	if hook.Module == "" || (hook.Guard == nil && hook.After == nil) {
+		panic(fmt.Sprintf("hook for migration %d must have a module and a guard or after function", hook.Version))
+	}
+	if hook.Direction != source.Up && hook.Direction != source.Down {
+		panic(fmt.Sprintf("hook for migration %d has invalid direction %q", hook.Version, hook.Direction))
+	}
+	key := hookKey{version: hook.Version, direction: hook.Direction}
+	if hooks[hook.Module] == nil {
+		hooks[hook.Module] = make(map[hookKey]Hook)
+	}
+	if _, exists := hooks[hook.Module][key]; exists {
+		panic(fmt.Sprintf("hook for migration %d (%s) registered twice for module %s", hook.Version, hook.Direction, hook.Module))
+	}
+	hooks[hook.Module][key] = hook
+}
+
+// hookFor looks up the hook of a module's migration in one direction
+func hookFor(moduleName string, version uint, direction source.Direction) (Hook, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	hook, ok := hooks[moduleName][hookKey{version: version, direction: direction}]
+	return hook, ok
+}
+
+// hookVersions returns the versions a module has hooks for, in order
+func hookVersions(moduleName string) []uint {
+	mu.Lock()
+	defer mu.Unlock()
+
+	seen := make(map[uint]bool)
+	var versions []uint
+	for key := range hooks[moduleName] {
+		if !seen[key.version] {
+			seen[key.version] = true
+			versions = append(versions, key.version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// runGuard runs the guard of a migration about to start, if it has one
+func runGuard(conn *sql.Conn, moduleName string, step *migrationStep) error {
+	hook, ok := hookFor(moduleName, step.version, step.direction)
+	if !ok || hook.Guard == nil {
+		return nil
+	}
+	if err := hook.Guard(runContext, conn); err != nil {
+		return fmt.Errorf("guard of migration %d (%s) refused to run it: %v", step.version, step.direction, err)
+	}
+	return nil
+}
+
+// runAfter runs the post hook of a migration that just succeeded, if it has one
+func runAfter(conn *sql.Conn, moduleName string, step *migrationStep) error {
+	hook, ok := hookFor(moduleName, step.version, step.direction)
+	if !ok || hook.After == nil {
+		return nil
+	}
+	if err := hook.After(runContext, conn); err != nil {
+		return fmt.Errorf("migration %d (%s) succeeded but its post hook failed: %v", step.version, step.direction, err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+func TestHooks_GuardRefusesAndLintFlagsOrphans(t *testing.T) {
+	module := testModule{name: "hooks_test", files: fstest.MapFS{
+		"20250101000000_drop_legacy.up.sql":   {Data: []byte("ALTER TABLE users DROP COLUMN legacy;")},
+		"20250101000000_drop_legacy.down.sql": {Data: []byte("ALTER TABLE users ADD COLUMN legacy INT;")},
+	}}
+	RegisterHook(Hook{Module: module.name, Version: 20250101000000, Guard: func(ctx context.Context, conn *sql.Conn) error {
+		return errors.New("users has more than 100000 rows")
+	}})
+	RegisterHook(Hook{Module: module.name, Version: 20250102000000, After: func(ctx context.Context, conn *sql.Conn) error { return nil }})
+
+	err := runGuard(nil, module.name, &migrationStep{version: 20250101000000, direction: source.Up})
+	if err == nil || !strings.Contains(err.Error(), "more than 100000 rows") {
+		t.Errorf("Expected the guard to refuse, got %v", err)
+	}
+	if err := runGuard(nil, module.name, &migrationStep{version: 20250101000000, direction: source.Down}); err != nil {
+		t.Errorf("Expected no guard for the down migration, got %v", err)
+	}
+
+	issues, _, err := lintModule(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "version 20250102000000, which has no migration") {
+		t.Errorf("Expected the orphaned hook to be reported, got %+v", issues)
+	}
+}
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"auto_verse/schema"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// LegacyVersionTable is the version table every module shared before each got its own
+const LegacyVersionTable = "schema_migrations"
+
+// AdoptLegacyVersions carries a database migrated before the per-module version tables over to them.
+// Every module without its own version table is forced to its latest migration at or before the
+// version in schema_migrations, which is then renamed to schema_migrations_legacy so this runs once.
+// It assumes every module was up to date when the shared table was last written.
+func AdoptLegacyVersions(db *sql.DB) error {
+	modules, err := app.Ordered()
+	if err != nil {
+		return fmt.Errorf("failed to order modules: %v", err)
+	}
+	return adoptLegacyVersions(db, modules)
+}
+
+// adoptLegacyVersions carries the legacy version table over to the version tables of modules
+func adoptLegacyVersions(db *sql.DB, modules []app.Module) error {
+	legacy, dirty, err := readVersion(db, LegacyVersionTable)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", LegacyVersionTable, err)
+	}
+	if dirty {
+		return fmt.Errorf("%s is dirty at version %s; fix the database and clear the dirty flag before migrating",
+			LegacyVersionTable, formatVersion(legacy))
+	}
+	if legacy == nil {
+		return nil
+	}
+
+	for _, module := range modules {
+		exists, err := schema.TableExists(db, VersionTable(module.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to check version table of module %s: %v", module.Name(), err)
+		}
+		if exists {
+			continue
+		}
+
+		target, err := targetAt(module, nil, *legacy)
+		if err != nil {
+			return err
+		}
+		if target.version == nil {
+			continue
+		}
+		if err := forceModule(db, module, int(*target.version)); err != nil {
+			return err
+		}
+		log.Printf("Module %s adopted version %d from %s", module.Name(), *target.version, LegacyVersionTable)
+	}
+
+	if _, err := db.Exec("RENAME TABLE `" + LegacyVersionTable + "` TO `" + LegacyVersionTable + "_legacy`"); err != nil {
+		return fmt.Errorf("failed to retire %s: %v", LegacyVersionTable, err)
+	}
+	return nil
+}
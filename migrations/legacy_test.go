@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAdoptLegacyVersions_ForcesModulesWithoutTheirOwnTable(t *testing.T) {
+	posts := testModule{name: "legacy_posts_test", files: fstest.MapFS{
+		"1_create_posts.up.sql": {Data: []byte("CREATE TABLE posts (id INT);")},
+		"3_add_title.up.sql":    {Data: []byte("ALTER TABLE posts ADD COLUMN title TEXT;")},
+	}}
+	users := testModule{name: "legacy_users_test", files: fstest.MapFS{
+		"1_create_users.up.sql": {Data: []byte("CREATE TABLE users (id INT);")},
+		"2_add_name.up.sql":     {Data: []byte("ALTER TABLE users ADD COLUMN name TEXT;")},
+	}}
+	later := testModule{name: "legacy_later_test", files: fstest.MapFS{
+		"5_create_tags.up.sql": {Data: []byte("CREATE TABLE tags (id INT);")},
+	}}
+	modules := []app.Module{posts, users, later}
+
+	db, mem := openMemDB(t)
+	if _, err := db.Exec("CREATE TABLE `" + LegacyVersionTable + "` (version bigint not null primary key, dirty boolean not null)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO `"+LegacyVersionTable+"` (version, dirty) VALUES (?, ?)", 2, false); err != nil {
+		t.Fatal(err)
+	}
+	// A module that already tracks its own version keeps it
+	setVersion(t, db, users.name, 1)
+
+	if err := adoptLegacyVersions(db, modules); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		module string
+		want   string
+	}{{posts.name, "1"}, {users.name, "1"}, {later.name, "none"}} {
+		version, dirty, err := readVersion(db, VersionTable(tt.module))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if formatVersion(version) != tt.want || dirty {
+			t.Errorf("Expected module %s at version %s, got %s (dirty %t)", tt.module, tt.want, formatVersion(version), dirty)
+		}
+	}
+	if mem.hasTable(LegacyVersionTable) || !mem.hasTable(LegacyVersionTable+"_legacy") {
+		t.Errorf("Expected %s to be renamed to %s_legacy", LegacyVersionTable, LegacyVersionTable)
+	}
+
+	// Once retired, the legacy table is left alone
+	if err := adoptLegacyVersions(db, modules); err != nil {
+		t.Errorf("Expected a second adoption to do nothing, got %v", err)
+	}
+}
+
+func TestAdoptLegacyVersions_RefusesDirtyTable(t *testing.T) {
+	db, mem := openMemDB(t)
+	if _, err := db.Exec("CREATE TABLE `" + LegacyVersionTable + "` (version bigint not null primary key, dirty boolean not null)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO `"+LegacyVersionTable+"` (version, dirty) VALUES (?, ?)", 2, true); err != nil {
+		t.Fatal(err)
+	}
+
+	err := adoptLegacyVersions(db, nil)
+	if err == nil || !strings.Contains(err.Error(), "dirty at version 2") {
+		t.Errorf("Expected a dirty legacy table to be refused, got %v", err)
+	}
+	if !mem.hasTable(LegacyVersionTable) {
+		t.Error("Expected the dirty legacy table to be left in place")
+	}
+}
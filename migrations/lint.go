@@ -0,0 +1,291 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Lint severities; only errors make the lint command fail
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+)
+
+// LintIssue is a problem found in a module's migrations
+type LintIssue struct {
+	Module   string `json:"module"`
+	File     string `json:"file"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// migrationName is the naming scheme the runner expects: <version>_<snake_case_name>.(up|down).sql
+var migrationName = regexp.MustCompile(`^([0-9]+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// timestampVersion matches the YYYYMMDDHHMMSS versions produced by make create-migration
+var timestampVersion = regexp.MustCompile(`^[0-9]{14}$`)
+
+// dialectRule flags SQL that the configured database does not understand
+type dialectRule struct {
+	pattern *regexp.Regexp
+	message string
+}
+
+// mysqlRules catches syntax from other databases that MySQL rejects
+var mysqlRules = []dialectRule{
+	{regexp.MustCompile(`(?i)\buuid_generate_v4\s*\(`), "uuid_generate_v4() is PostgreSQL; use (UUID()) in MySQL"},
+	{regexp.MustCompile(`(?i)\bgen_random_uuid\s*\(`), "gen_random_uuid() is PostgreSQL; use (UUID()) in MySQL"},
+	{regexp.MustCompile(`(?im)^\s*[a-z_][a-z0-9_]*\s+UUID\b[ \t]*([^( \t]|$)`), "MySQL has no UUID column type; use CHAR(36)"},
+	{regexp.MustCompile(`(?i)\b(BIG)?SERIAL\b`), "SERIAL columns are PostgreSQL; use INT AUTO_INCREMENT in MySQL"},
+	{regexp.MustCompile(`(?i)\bJSONB\b`), "JSONB is PostgreSQL; use JSON in MySQL"},
+	{regexp.MustCompile(`(?i)\bBYTEA\b`), "BYTEA is PostgreSQL; use BLOB in MySQL"},
+	{regexp.MustCompile(`(?i)\bTIMESTAMPTZ\b`), "TIMESTAMPTZ is PostgreSQL; use TIMESTAMP in MySQL"},
+	{regexp.MustCompile(`(?i)\bAUTOINCREMENT\b`), "AUTOINCREMENT is SQLite; use AUTO_INCREMENT in MySQL"},
+	{regexp.MustCompile(`[a-zA-Z0-9_)]::[a-zA-Z]`), ":: casts are PostgreSQL; use CAST(... AS ...) in MySQL"},
+}
+
+// postgresRules catches MySQL and SQLite syntax that PostgreSQL rejects
+var postgresRules = []dialectRule{
+	{regexp.MustCompile(`(?i)\bAUTO_?INCREMENT\b`), "AUTO_INCREMENT is MySQL; use GENERATED ALWAYS AS IDENTITY or a UUID default in PostgreSQL"},
+	{regexp.MustCompile("`"), "backtick quoted names are MySQL; use double quotes in PostgreSQL"},
+	{regexp.MustCompile(`(?i)\(\s*UUID\s*\(\s*\)\s*\)`), "UUID() is MySQL; use gen_random_uuid() in PostgreSQL"},
+	{regexp.MustCompile(`(?i)\bON\s+UPDATE\s+CURRENT_TIMESTAMP\b`), "ON UPDATE CURRENT_TIMESTAMP is MySQL; use a trigger in PostgreSQL"},
+	{regexp.MustCompile(`(?i)\bENGINE\s*=`), "ENGINE= is MySQL; PostgreSQL has no storage engines"},
+	{regexp.MustCompile(`(?i)\bDATETIME\b`), "DATETIME is MySQL; use TIMESTAMP or TIMESTAMPTZ in PostgreSQL"},
+	{regexp.MustCompile(`(?i)\bDROP\s+INDEX\s+\S+\s+ON\b`), "DROP INDEX ... ON is MySQL; PostgreSQL drops an index by name"},
+}
+
+// sqliteRules catches MySQL and PostgreSQL syntax that SQLite rejects
+var sqliteRules = []dialectRule{
+	{regexp.MustCompile(`(?i)\bAUTO_INCREMENT\b`), "AUTO_INCREMENT is MySQL; use INTEGER PRIMARY KEY AUTOINCREMENT in SQLite"},
+	{regexp.MustCompile(`(?i)\b(BIG)?SERIAL\b`), "SERIAL columns are PostgreSQL; use INTEGER PRIMARY KEY in SQLite"},
+	{regexp.MustCompile(`(?i)\buuid_generate_v4\s*\(|\bgen_random_uuid\s*\(|\(\s*UUID\s*\(\s*\)\s*\)`), "SQLite has no UUID function; generate the id from randomblob()"},
+	{regexp.MustCompile(`(?i)\bON\s+UPDATE\s+CURRENT_TIMESTAMP\b`), "ON UPDATE CURRENT_TIMESTAMP is MySQL; use a trigger in SQLite"},
+	{regexp.MustCompile(`(?i)\bENGINE\s*=`), "ENGINE= is MySQL; SQLite has no storage engines"},
+	{regexp.MustCompile(`[a-zA-Z0-9_)]::[a-zA-Z]`), ":: casts are PostgreSQL; use CAST(... AS ...) in SQLite"},
+}
+
+// dialectRules holds the rules for each dialect migrations can be written for
+var dialectRules = map[string][]dialectRule{
+	"mysql":    mysqlRules,
+	"postgres": postgresRules,
+	"sqlite":   sqliteRules,
+}
+
+// Lint checks the migrations of every registered module, including versions shared across modules
+func Lint() ([]LintIssue, error) {
+	modules, err := app.Ordered()
+	if err != nil {
+		return nil, fmt.Errorf("failed to order modules: %v", err)
+	}
+
+	var issues []LintIssue
+	owners := make(map[uint][]string)
+	for _, module := range modules {
+		moduleIssues, versions, err := lintModule(module)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, moduleIssues...)
+		for _, version := range versions {
+			owners[version] = append(owners[version], module.Name())
+		}
+	}
+
+	// Versions are timestamps, so the same version in two modules is almost always a copy-paste
+	versions := make([]uint, 0, len(owners))
+	for version := range owners {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	for _, version := range versions {
+		if len(owners[version]) > 1 {
+			issues = append(issues, LintIssue{
+				Module:   strings.Join(owners[version], ","),
+				Severity: LintError,
+				Message:  fmt.Sprintf("version %d is used by modules %s", version, strings.Join(owners[version], " and ")),
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// LintModule checks the migrations of a single module
+func LintModule(moduleName string) ([]LintIssue, error) {
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	issues, _, err := lintModule(module)
+	return issues, err
+}
+
+// HasLintErrors reports whether any issue is severe enough to fail the lint
+func HasLintErrors(issues []LintIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// lintModule checks one module and returns the versions it defines
+func lintModule(module app.Module) ([]LintIssue, []uint, error) {
+	issues, versions, err := lintFiles(module.Name(), lintFS(module))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lint migrations for module %s: %v", module.Name(), err)
+	}
+
+	// Go migrations share the version space with the SQL files
+	goFiles, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[uint]bool, len(versions))
+	for _, version := range versions {
+		seen[version] = true
+	}
+	for _, file := range goFiles {
+		if !isGoMigration(file) {
+			continue
+		}
+		if seen[file.Version] {
+			issues = append(issues, LintIssue{Module: module.Name(), File: file.Raw, Severity: LintError,
+				Message: fmt.Sprintf("version %d is used by both a Go migration and a SQL file", file.Version)})
+			continue
+		}
+		versions = append(versions, file.Version)
+	}
+
+	// Hooks must belong to a migration
+	for _, version := range hookVersions(module.Name()) {
+		if !slices.Contains(versions, version) {
+			issues = append(issues, LintIssue{Module: module.Name(), File: fmt.Sprintf("%d", version), Severity: LintError,
+				Message: fmt.Sprintf("hook registered for version %d, which has no migration", version)})
+		}
+	}
+
+	return issues, versions, nil
+}
+
+// lintFS prefers the migrations directory on disk, which also shows files the binary doesn't embed
+func lintFS(module app.Module) fs.FS {
+	if info, err := os.Stat(module.MigrationsDir()); err == nil && info.IsDir() {
+		return os.DirFS(module.MigrationsDir())
+	}
+	return moduleFS(module)
+}
+
+// lintFiles checks naming, up/down pairing, duplicates and dialect of a module's migration files.
+// The SQL is checked against the dialect set with SetDialect.
+func lintFiles(moduleName string, fsys fs.FS) ([]LintIssue, []uint, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	var issues []LintIssue
+	report := func(file, severity, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{Module: moduleName, File: file, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// names[version][direction] holds the files defining each version
+	names := make(map[uint]map[string][]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			report(name, LintError, "unexpected directory in migrations")
+			continue
+		}
+
+		if !strings.HasSuffix(name, ".sql") {
+			report(name, LintError, "stray file in migrations; only .up.sql and .down.sql files belong here")
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		statements := stripComments(string(content))
+		for _, rule := range dialectRules[dialect.Name] {
+			if rule.pattern.MatchString(statements) {
+				report(name, LintError, "%s", rule.message)
+			}
+		}
+
+		match := migrationName.FindStringSubmatch(name)
+		if match == nil {
+			report(name, LintError, "file name must look like <version>_<name>.up.sql or <version>_<name>.down.sql; golang-migrate ignores it")
+			continue
+		}
+		if strings.TrimSpace(statements) == "" {
+			report(name, LintError, "migration contains no SQL statements")
+		}
+		if strings.Contains(string(content), placeholderMarker) {
+			report(name, LintWarning, "migration still contains the placeholder make:migration wrote")
+		}
+		if _, err := parsePhase(string(content)); err != nil {
+			report(name, LintError, "%v", err)
+		}
+
+		m, err := source.Parse(name)
+		if err != nil {
+			report(name, LintError, "version is not a valid number: %v", err)
+			continue
+		}
+		if !timestampVersion.MatchString(match[1]) {
+			report(name, LintWarning, "version %s is not a YYYYMMDDHHMMSS timestamp, so it won't order correctly against other modules", match[1])
+		}
+		if names[m.Version] == nil {
+			names[m.Version] = make(map[string][]string)
+		}
+		names[m.Version][match[3]] = append(names[m.Version][match[3]], name)
+	}
+
+	versions := make([]uint, 0, len(names))
+	for version := range names {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	for _, version := range versions {
+		files := names[version]
+		for _, direction := range []string{"up", "down"} {
+			if len(files[direction]) > 1 {
+				report(strings.Join(files[direction], ", "), LintError, "version %d has more than one %s migration", version, direction)
+			}
+		}
+		switch {
+		case len(files["up"]) == 0:
+			report(files["down"][0], LintError, "down migration has no matching up migration")
+		case len(files["down"]) == 0:
+			report(files["up"][0], LintError, "up migration has no matching down migration")
+		case strings.TrimSuffix(files["up"][0], ".up.sql") != strings.TrimSuffix(files["down"][0], ".down.sql"):
+			report(files["down"][0], LintWarning, "down migration name does not match %s", files["up"][0])
+		}
+	}
+
+	return issues, versions, nil
+}
+
+// stripComments removes -- line comments so rules only look at SQL
+func stripComments(sql string) string {
+	lines := strings.Split(sql, "\n")
+	for i, line := range lines {
+		if idx := strings.Index(line, "--"); idx != -1 {
+			lines[i] = line[:idx]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
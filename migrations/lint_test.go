@@ -0,0 +1,111 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLintFiles_ReportsBrokenMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_initial_migration.sql":                 {Data: []byte("CREATE TABLE IF NOT EXISTS users (\n    id UUID PRIMARY KEY DEFAULT uuid_generate_v4()\n);")},
+		"20250308002807_create_users_table.up.sql":   {Data: []byte("CREATE TABLE users (id CHAR(36) PRIMARY KEY DEFAULT (UUID()));")},
+		"20250308002807_create_users_table.down.sql": {Data: []byte("DROP TABLE IF EXISTS users;")},
+		"20250308002955_users_details.up.sql":        {Data: []byte("CREATE TABLE users_details (id INT);")},
+		"notes.txt":                                  {Data: []byte("todo")},
+	}
+
+	issues, versions, err := lintFiles("users", fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"0001_initial_migration.sql: file name must look like",
+		"0001_initial_migration.sql: uuid_generate_v4() is PostgreSQL",
+		"0001_initial_migration.sql: MySQL has no UUID column type",
+		"20250308002955_users_details.up.sql: up migration has no matching down migration",
+		"notes.txt: stray file",
+	}
+	for _, want := range expected {
+		found := false
+		for _, issue := range issues {
+			if strings.HasPrefix(issue.File+": "+issue.Message, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected an issue starting with %q, got %+v", want, issues)
+		}
+	}
+	if len(issues) != len(expected) {
+		t.Errorf("Expected %d issues, got %d: %+v", len(expected), len(issues), issues)
+	}
+
+	if len(versions) != 2 {
+		t.Errorf("Expected 2 versions, got %v", versions)
+	}
+}
+
+func TestLintFiles_AcceptsGeneratedSQLForEachDialect(t *testing.T) {
+	defer SetDialect("mysql")
+
+	for _, name := range []string{"mysql", "postgres", "sqlite"} {
+		if err := SetDialect(name); err != nil {
+			t.Fatal(err)
+		}
+		description := "create_posts"
+		createUp, createDown, err := migrationStatements(&description, MigrationOptions{
+			Create:         "posts",
+			AddColumns:     []string{"title:VARCHAR(255) NOT NULL", "user_id:CHAR(36)"},
+			AddIndexes:     []string{"title"},
+			AddForeignKeys: []string{"user_id:users.id:cascade"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		description = "add_slug"
+		alterUp, alterDown, err := migrationStatements(&description, MigrationOptions{
+			Table:      "posts",
+			AddColumns: []string{"slug:VARCHAR(255)"},
+			AddIndexes: []string{"slug"},
+			Unique:     true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fsys := fstest.MapFS{
+			"20250101000000_create_posts.up.sql":   {Data: []byte(strings.Join(createUp, "\n\n"))},
+			"20250101000000_create_posts.down.sql": {Data: []byte(strings.Join(createDown, "\n\n"))},
+			"20250102000000_add_slug.up.sql":       {Data: []byte(strings.Join(alterUp, "\n\n"))},
+			"20250102000000_add_slug.down.sql":     {Data: []byte(strings.Join(alterDown, "\n\n"))},
+		}
+		issues, _, err := lintFiles("posts", fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) != 0 {
+			t.Errorf("Expected the %s migrations to lint clean, got %+v", name, issues)
+		}
+	}
+}
+
+func TestLintFiles_ChecksTheConfiguredDialect(t *testing.T) {
+	defer SetDialect("mysql")
+	fsys := fstest.MapFS{
+		"20250101000000_create_posts.up.sql":   {Data: []byte("CREATE TABLE posts (id INT AUTO_INCREMENT PRIMARY KEY, created_at DATETIME);")},
+		"20250101000000_create_posts.down.sql": {Data: []byte("DROP TABLE posts;")},
+	}
+
+	if err := SetDialect("postgres"); err != nil {
+		t.Fatal(err)
+	}
+	issues, _, err := lintFiles("posts", fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 {
+		t.Errorf("Expected AUTO_INCREMENT and DATETIME to be reported for postgres, got %+v", issues)
+	}
+}
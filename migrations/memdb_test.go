@@ -0,0 +1,1119 @@
+package migrations
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode"
+)
+
+// memDatabase is an in-memory database that understands the small part of MySQL used by the runner,
+// by golang-migrate's mysql driver and by the migrations written in tests. Statements outside that
+// part fail, so a test notices when the code under test starts relying on something new.
+type memDatabase struct {
+	mu         sync.Mutex
+	name       string
+	tables     map[string]*memTable
+	statements []memRun // every statement run, in order
+	conns      int64
+}
+
+// memTable holds the rows of a table keyed by lower case column name
+type memTable struct {
+	columns    []memColumn
+	primaryKey []string
+	rows       []map[string]driver.Value
+	lastID     int64
+}
+
+// memRun is a statement and the id of the connection that ran it
+type memRun struct {
+	text string
+	conn int64
+}
+
+type memColumn struct {
+	name          string
+	value         driver.Value // default value
+	defaultNow    bool
+	autoIncrement bool
+	primaryKey    bool
+}
+
+var (
+	memMu        sync.Mutex
+	memDatabases = make(map[string]*memDatabase)
+)
+
+func init() {
+	sql.Register("migrations_memdb", memDriver{})
+}
+
+// openMemDB opens a connection pool to a fresh, empty in-memory database named after the test
+func openMemDB(t *testing.T) (*sql.DB, *memDatabase) {
+	t.Helper()
+	mem := &memDatabase{name: t.Name(), tables: make(map[string]*memTable)}
+	memMu.Lock()
+	memDatabases[mem.name] = mem
+	memMu.Unlock()
+
+	db, err := sql.Open("migrations_memdb", mem.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		memMu.Lock()
+		delete(memDatabases, mem.name)
+		memMu.Unlock()
+	})
+	return db, mem
+}
+
+// hasTable reports whether a table exists
+func (m *memDatabase) hasTable(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.tables[name]
+	return ok
+}
+
+// ranOn returns the id of the connection that first ran a statement containing text, or 0 if none did
+func (m *memDatabase) ranOn(text string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, statement := range m.statements {
+		if strings.Contains(statement.text, text) {
+			return statement.conn
+		}
+	}
+	return 0
+}
+
+// memDriver opens connections to the in-memory database with the given name, creating it if needed
+type memDriver struct{}
+
+func (memDriver) Open(name string) (driver.Conn, error) {
+	memMu.Lock()
+	defer memMu.Unlock()
+	mem, ok := memDatabases[name]
+	if !ok {
+		mem = &memDatabase{name: name, tables: make(map[string]*memTable)}
+		memDatabases[name] = mem
+	}
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	mem.conns++
+	return &memConn{db: mem, id: mem.conns}, nil
+}
+
+type memConn struct {
+	db *memDatabase
+	id int64
+}
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *memConn) Close() error                              { return nil }
+func (c *memConn) Begin() (driver.Tx, error)                 { return memTx{}, nil }
+
+func (c *memConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return memTx{}, nil
+}
+
+// memTx applies statements as they run; the runner only uses transactions for single version updates
+type memTx struct{}
+
+func (memTx) Commit() error   { return nil }
+func (memTx) Rollback() error { return nil }
+
+func (c *memConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	statements, err := splitStatements(query)
+	if err != nil {
+		return nil, err
+	}
+	var affected int64
+	for _, statement := range statements {
+		p := &memParser{conn: c, tokens: statement.tokens, args: args}
+		c.db.statements = append(c.db.statements, memRun{text: statement.text, conn: c.id})
+		n, _, err := p.run()
+		if err != nil {
+			return nil, fmt.Errorf("%v in %q", err, statement.text)
+		}
+		affected += n
+		args = args[p.used:]
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (c *memConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	statements, err := splitStatements(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(statements) != 1 {
+		return nil, fmt.Errorf("query must be a single statement: %q", query)
+	}
+	c.db.statements = append(c.db.statements, memRun{text: statements[0].text, conn: c.id})
+	p := &memParser{conn: c, tokens: statements[0].tokens, args: args}
+	_, rows, err := p.run()
+	if err != nil {
+		return nil, fmt.Errorf("%v in %q", err, query)
+	}
+	if rows == nil {
+		rows = &memRows{}
+	}
+	return rows, nil
+}
+
+type memRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *memRows) Columns() []string { return r.columns }
+func (r *memRows) Close() error      { return nil }
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// memToken is a word, quoted name, string, number or symbol of a statement
+type memToken struct {
+	text   string
+	quoted bool // a 'string' literal
+}
+
+type memStatement struct {
+	text   string
+	tokens []memToken
+}
+
+// splitStatements tokenizes a query and splits it on semicolons, dropping comments and empty statements
+func splitStatements(query string) ([]memStatement, error) {
+	var statements []memStatement
+	var tokens []memToken
+	start := 0
+	flush := func(end int) {
+		if len(tokens) > 0 {
+			statements = append(statements, memStatement{text: strings.TrimSpace(query[start:end]), tokens: tokens})
+		}
+		tokens = nil
+	}
+
+	for i := 0; i < len(query); {
+		ch := rune(query[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case strings.HasPrefix(query[i:], "--") || ch == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i:], "*/")
+			if end < 0 {
+				return nil, errors.New("unterminated comment")
+			}
+			i += end + 2
+		case ch == ';':
+			flush(i)
+			i++
+			start = i
+		case ch == '`':
+			end := strings.IndexByte(query[i+1:], '`')
+			if end < 0 {
+				return nil, errors.New("unterminated quoted name")
+			}
+			if len(tokens) == 0 {
+				start = i
+			}
+			tokens = append(tokens, memToken{text: query[i+1 : i+1+end]})
+			i += end + 2
+		case ch == '\'' || ch == '"':
+			var text strings.Builder
+			j := i + 1
+			for ; j < len(query); j++ {
+				if query[j] == '\\' && j+1 < len(query) {
+					j++
+					text.WriteByte(query[j])
+					continue
+				}
+				if query[j] == query[i] {
+					if j+1 < len(query) && query[j+1] == query[i] {
+						j++
+						text.WriteByte(query[j])
+						continue
+					}
+					break
+				}
+				text.WriteByte(query[j])
+			}
+			if j >= len(query) {
+				return nil, errors.New("unterminated string")
+			}
+			if len(tokens) == 0 {
+				start = i
+			}
+			tokens = append(tokens, memToken{text: text.String(), quoted: true})
+			i = j + 1
+		default:
+			j := i + 1
+			if isWordChar(ch) {
+				for j < len(query) && isWordChar(rune(query[j])) {
+					j++
+				}
+			} else if j < len(query) {
+				switch query[i : j+1] {
+				case "<=", ">=", "<>", "!=":
+					j++
+				}
+			}
+			if len(tokens) == 0 {
+				start = i
+			}
+			tokens = append(tokens, memToken{text: query[i:j]})
+			i = j
+		}
+	}
+	flush(len(query))
+	return statements, nil
+}
+
+func isWordChar(ch rune) bool {
+	return ch == '_' || ch == '.' || ch == '$' || unicode.IsLetter(ch) || unicode.IsDigit(ch)
+}
+
+// memParser runs one statement, binding its placeholders to args in order
+type memParser struct {
+	conn   *memConn
+	tokens []memToken
+	pos    int
+	args   []driver.NamedValue
+	used   int
+}
+
+func (p *memParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *memParser) next() memToken {
+	if p.pos >= len(p.tokens) {
+		return memToken{}
+	}
+	p.pos++
+	return p.tokens[p.pos-1]
+}
+
+// accept consumes the given words, case-insensitively, if they come next
+func (p *memParser) accept(words ...string) bool {
+	for i, word := range words {
+		if p.pos+i >= len(p.tokens) || p.tokens[p.pos+i].quoted || !strings.EqualFold(p.tokens[p.pos+i].text, word) {
+			return false
+		}
+	}
+	p.pos += len(words)
+	return true
+}
+
+func (p *memParser) expect(words ...string) error {
+	if !p.accept(words...) {
+		return fmt.Errorf("expected %s at %q", strings.Join(words, " "), p.peek())
+	}
+	return nil
+}
+
+// name reads a table or column name
+func (p *memParser) name() (string, error) {
+	token := p.next()
+	if token.text == "" || token.quoted {
+		return "", fmt.Errorf("expected a name at %q", token.text)
+	}
+	return token.text, nil
+}
+
+// names reads a parenthesised, comma separated list of column names
+func (p *memParser) names() ([]string, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, strings.ToLower(name))
+		if p.accept(")") {
+			return names, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// group returns the tokens up to the parenthesis closing the one just consumed
+func (p *memParser) group() ([]memToken, error) {
+	depth := 1
+	for i := p.pos; i < len(p.tokens); i++ {
+		switch p.tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				group := p.tokens[p.pos:i]
+				p.pos = i + 1
+				return group, nil
+			}
+		}
+	}
+	return nil, errors.New("unbalanced parenthesis")
+}
+
+func (p *memParser) table(name string) (*memTable, error) {
+	table, ok := p.conn.db.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("table %s doesn't exist", name)
+	}
+	return table, nil
+}
+
+// run executes the statement, returning the rows affected or the rows selected
+func (p *memParser) run() (int64, *memRows, error) {
+	var affected int64
+	var rows *memRows
+	var err error
+	switch {
+	case p.accept("SET"), p.accept("KILL"):
+		p.pos = len(p.tokens)
+	case p.accept("CREATE", "TABLE"):
+		err = p.createTable()
+	case p.accept("DROP", "TABLE"):
+		err = p.dropTable()
+	case p.accept("ALTER", "TABLE"):
+		err = p.alterTable()
+	case p.accept("RENAME", "TABLE"):
+		err = p.renameTable()
+	case p.accept("INSERT", "INTO"):
+		affected, err = p.insert()
+	case p.accept("UPDATE"):
+		affected, err = p.update()
+	case p.accept("DELETE", "FROM"):
+		affected, err = p.delete()
+	case p.accept("SHOW", "TABLES", "LIKE"):
+		rows, err = p.showTables()
+	case p.accept("SELECT"):
+		rows, err = p.selectRows()
+	default:
+		err = errors.New("unsupported statement")
+	}
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.peek())
+	}
+	return affected, rows, err
+}
+
+func (p *memParser) createTable() error {
+	ifNotExists := p.accept("IF", "NOT", "EXISTS")
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("("); err != nil {
+		return err
+	}
+	definitions, err := p.group()
+	if err != nil {
+		return err
+	}
+	p.pos = len(p.tokens) // table options
+
+	if _, exists := p.conn.db.tables[name]; exists {
+		if ifNotExists {
+			return nil
+		}
+		return fmt.Errorf("table %s already exists", name)
+	}
+
+	table := &memTable{}
+	depth, from := 0, 0
+	for i := 0; i <= len(definitions); i++ {
+		if i < len(definitions) {
+			switch definitions[i].text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			if definitions[i].text != "," || depth > 0 {
+				continue
+			}
+		}
+		if err := table.define(&memParser{conn: p.conn, tokens: definitions[from:i]}); err != nil {
+			return err
+		}
+		from = i + 1
+	}
+	p.conn.db.tables[name] = table
+	return nil
+}
+
+// define adds a column or primary key definition to a table being created
+func (t *memTable) define(p *memParser) error {
+	switch {
+	case p.accept("PRIMARY", "KEY"):
+		columns, err := p.names()
+		t.primaryKey = columns
+		return err
+	case p.accept("INDEX"), p.accept("KEY"), p.accept("UNIQUE"), p.accept("CONSTRAINT"), p.accept("FOREIGN"), p.accept("FULLTEXT"):
+		return nil
+	}
+	column, err := p.column()
+	if err != nil {
+		return err
+	}
+	if column.primaryKey {
+		t.primaryKey = []string{column.name}
+	}
+	t.columns = append(t.columns, column)
+	return nil
+}
+
+// column reads a column definition, keeping its name, default and auto increment
+func (p *memParser) column() (memColumn, error) {
+	name, err := p.name()
+	if err != nil {
+		return memColumn{}, err
+	}
+	column := memColumn{name: strings.ToLower(name)}
+	for p.pos < len(p.tokens) {
+		switch {
+		case p.accept("AUTO_INCREMENT"):
+			column.autoIncrement = true
+		case p.accept("PRIMARY", "KEY"):
+			column.primaryKey = true
+		case p.accept("DEFAULT", "CURRENT_TIMESTAMP"):
+			column.defaultNow = true
+		case p.accept("DEFAULT"):
+			value, err := p.expr()
+			if err != nil {
+				return column, err
+			}
+			column.value = value.value
+		default:
+			p.next()
+		}
+	}
+	return column, nil
+}
+
+func (p *memParser) dropTable() error {
+	ifExists := p.accept("IF", "EXISTS")
+	for {
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if _, exists := p.conn.db.tables[name]; !exists && !ifExists {
+			return fmt.Errorf("table %s doesn't exist", name)
+		}
+		delete(p.conn.db.tables, name)
+		if !p.accept(",") {
+			return nil
+		}
+	}
+}
+
+func (p *memParser) alterTable() error {
+	name, err := p.name()
+	if err != nil {
+		return err
+	}
+	table, err := p.table(name)
+	if err != nil {
+		return err
+	}
+	switch {
+	case p.accept("ADD", "INDEX"), p.accept("ADD", "KEY"), p.accept("ADD", "UNIQUE"), p.accept("ADD", "CONSTRAINT"):
+		p.pos = len(p.tokens)
+	case p.accept("ADD", "COLUMN"), p.accept("ADD"):
+		column, err := p.column()
+		if err != nil {
+			return err
+		}
+		table.columns = append(table.columns, column)
+	case p.accept("DROP", "COLUMN"), p.accept("DROP"):
+		column, err := p.name()
+		if err != nil {
+			return err
+		}
+		for i := range table.columns {
+			if table.columns[i].name == strings.ToLower(column) {
+				table.columns = append(table.columns[:i], table.columns[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("table %s has no column %s", name, column)
+	default:
+		return errors.New("unsupported ALTER TABLE")
+	}
+	return nil
+}
+
+func (p *memParser) renameTable() error {
+	from, err := p.name()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("TO"); err != nil {
+		return err
+	}
+	to, err := p.name()
+	if err != nil {
+		return err
+	}
+	table, err := p.table(from)
+	if err != nil {
+		return err
+	}
+	if _, exists := p.conn.db.tables[to]; exists {
+		return fmt.Errorf("table %s already exists", to)
+	}
+	delete(p.conn.db.tables, from)
+	p.conn.db.tables[to] = table
+	return nil
+}
+
+// memExpr is a value in a statement: a literal or bound argument, a column of the row, or VALUES(column)
+type memExpr struct {
+	value    driver.Value
+	column   string
+	inserted bool
+}
+
+// eval evaluates an expression against a row and, in ON DUPLICATE KEY UPDATE, the row being inserted
+func (e memExpr) eval(row, inserted map[string]driver.Value) driver.Value {
+	switch {
+	case e.inserted:
+		return inserted[e.column]
+	case e.column != "":
+		return row[e.column]
+	}
+	return e.value
+}
+
+func (p *memParser) expr() (memExpr, error) {
+	token := p.next()
+	switch {
+	case token.quoted:
+		return memExpr{value: token.text}, nil
+	case token.text == "?":
+		if p.used >= len(p.args) {
+			return memExpr{}, errors.New("missing argument")
+		}
+		p.used++
+		return memExpr{value: normalize(p.args[p.used-1].Value)}, nil
+	case token.text == "":
+		return memExpr{}, errors.New("expected a value")
+	case strings.EqualFold(token.text, "NULL"):
+		return memExpr{}, nil
+	case strings.EqualFold(token.text, "TRUE"):
+		return memExpr{value: true}, nil
+	case strings.EqualFold(token.text, "FALSE"):
+		return memExpr{value: false}, nil
+	case strings.EqualFold(token.text, "CURRENT_TIMESTAMP"), strings.EqualFold(token.text, "NOW") && p.accept("(", ")"):
+		return memExpr{value: time.Now()}, nil
+	case strings.EqualFold(token.text, "DATABASE") && p.accept("(", ")"):
+		return memExpr{value: p.conn.db.name}, nil
+	case strings.EqualFold(token.text, "CONNECTION_ID") && p.accept("(", ")"):
+		return memExpr{value: p.conn.id}, nil
+	case strings.EqualFold(token.text, "VALUES") && p.accept("("):
+		column, err := p.name()
+		if err != nil {
+			return memExpr{}, err
+		}
+		return memExpr{column: strings.ToLower(column), inserted: true}, p.expect(")")
+	case strings.EqualFold(token.text, "GET_LOCK"), strings.EqualFold(token.text, "RELEASE_LOCK"):
+		if err := p.expect("("); err != nil {
+			return memExpr{}, err
+		}
+		for !p.accept(")") {
+			if p.accept(",") {
+				continue
+			}
+			if _, err := p.expr(); err != nil {
+				return memExpr{}, err
+			}
+		}
+		return memExpr{value: true}, nil
+	}
+	if n, err := strconv.ParseInt(token.text, 10, 64); err == nil {
+		return memExpr{value: n}, nil
+	}
+	return memExpr{column: strings.ToLower(token.text)}, nil
+}
+
+// normalize stores every integer as an int64, as the mysql driver returns them
+func normalize(value driver.Value) driver.Value {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	}
+	return value
+}
+
+// compare orders two values, treating booleans as 0 and 1 like MySQL
+func compare(a, b driver.Value) int {
+	a, b = number(a), number(b)
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok {
+			return cmp.Compare(x, y)
+		}
+	}
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// number converts a boolean to the integer MySQL stores for it
+func number(value driver.Value) driver.Value {
+	if v, ok := value.(bool); ok {
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	}
+	return value
+}
+
+// memCondition is one comparison of a WHERE clause; clauses join them with AND
+type memCondition struct {
+	column string
+	op     string
+	value  memExpr
+}
+
+func (p *memParser) where() ([]memCondition, error) {
+	if !p.accept("WHERE") {
+		return nil, nil
+	}
+	var conditions []memCondition
+	for {
+		column, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		op := p.next().text
+		switch op {
+		case "=", "<>", "!=", "<", ">", "<=", ">=":
+		default:
+			return nil, fmt.Errorf("unsupported operator %q", op)
+		}
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, memCondition{column: strings.ToLower(column), op: op, value: value})
+		if !p.accept("AND") {
+			return conditions, nil
+		}
+	}
+}
+
+func matches(row map[string]driver.Value, conditions []memCondition) bool {
+	for _, condition := range conditions {
+		c := compare(row[condition.column], condition.value.eval(row, nil))
+		var ok bool
+		switch condition.op {
+		case "=":
+			ok = c == 0
+		case "<>", "!=":
+			ok = c != 0
+		case "<":
+			ok = c < 0
+		case ">":
+			ok = c > 0
+		case "<=":
+			ok = c <= 0
+		case ">=":
+			ok = c >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// limit reads an optional LIMIT clause, returning -1 when there is none
+func (p *memParser) limit() (int, error) {
+	if !p.accept("LIMIT") {
+		return -1, nil
+	}
+	value, err := p.expr()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := value.value.(int64)
+	if !ok {
+		return 0, fmt.Errorf("invalid limit %v", value.value)
+	}
+	return int(n), nil
+}
+
+func (p *memParser) insert() (int64, error) {
+	name, err := p.name()
+	if err != nil {
+		return 0, err
+	}
+	table, err := p.table(name)
+	if err != nil {
+		return 0, err
+	}
+	columns, err := p.names()
+	if err != nil {
+		return 0, err
+	}
+	if err := p.expect("VALUES"); err != nil {
+		return 0, err
+	}
+
+	var inserted []map[string]driver.Value
+	for {
+		if err := p.expect("("); err != nil {
+			return 0, err
+		}
+		row := make(map[string]driver.Value)
+		for i, column := range columns {
+			if i > 0 {
+				if err := p.expect(","); err != nil {
+					return 0, err
+				}
+			}
+			value, err := p.expr()
+			if err != nil {
+				return 0, err
+			}
+			row[column] = value.eval(nil, nil)
+		}
+		if err := p.expect(")"); err != nil {
+			return 0, err
+		}
+		inserted = append(inserted, row)
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	var updates map[string]memExpr
+	if p.accept("ON", "DUPLICATE", "KEY", "UPDATE") {
+		if updates, err = p.assignments(); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, values := range inserted {
+		row := table.newRow(values)
+		if existing := table.find(row); existing != nil {
+			if updates == nil {
+				return 0, errors.New("duplicate primary key")
+			}
+			for column, value := range updates {
+				existing[column] = value.eval(existing, row)
+			}
+			continue
+		}
+		table.rows = append(table.rows, row)
+	}
+	return int64(len(inserted)), nil
+}
+
+// newRow fills in the defaults and auto increment id of a row being inserted
+func (t *memTable) newRow(values map[string]driver.Value) map[string]driver.Value {
+	row := make(map[string]driver.Value, len(t.columns))
+	for _, column := range t.columns {
+		value, ok := values[column.name]
+		switch {
+		case ok:
+		case column.autoIncrement:
+			t.lastID++
+			value = t.lastID
+		case column.defaultNow:
+			value = time.Now()
+		default:
+			value = column.value
+		}
+		row[column.name] = value
+	}
+	return row
+}
+
+// find returns the stored row with the same primary key as row
+func (t *memTable) find(row map[string]driver.Value) map[string]driver.Value {
+	if len(t.primaryKey) == 0 {
+		return nil
+	}
+	for _, existing := range t.rows {
+		same := true
+		for _, column := range t.primaryKey {
+			same = same && compare(existing[column], row[column]) == 0
+		}
+		if same {
+			return existing
+		}
+	}
+	return nil
+}
+
+// assignments reads the column = value list of UPDATE and ON DUPLICATE KEY UPDATE
+func (p *memParser) assignments() (map[string]memExpr, error) {
+	assignments := make(map[string]memExpr)
+	for {
+		column, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		value, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		assignments[strings.ToLower(column)] = value
+		if !p.accept(",") {
+			return assignments, nil
+		}
+	}
+}
+
+func (p *memParser) update() (int64, error) {
+	name, err := p.name()
+	if err != nil {
+		return 0, err
+	}
+	table, err := p.table(name)
+	if err != nil {
+		return 0, err
+	}
+	if err := p.expect("SET"); err != nil {
+		return 0, err
+	}
+	assignments, err := p.assignments()
+	if err != nil {
+		return 0, err
+	}
+	conditions, err := p.where()
+	if err != nil {
+		return 0, err
+	}
+
+	var affected int64
+	for _, row := range table.rows {
+		if !matches(row, conditions) {
+			continue
+		}
+		for column, value := range assignments {
+			row[column] = value.eval(row, nil)
+		}
+		affected++
+	}
+	return affected, nil
+}
+
+func (p *memParser) delete() (int64, error) {
+	name, err := p.name()
+	if err != nil {
+		return 0, err
+	}
+	table, err := p.table(name)
+	if err != nil {
+		return 0, err
+	}
+	conditions, err := p.where()
+	if err != nil {
+		return 0, err
+	}
+	limit, err := p.limit()
+	if err != nil {
+		return 0, err
+	}
+
+	var kept []map[string]driver.Value
+	var affected int64
+	for _, row := range table.rows {
+		if matches(row, conditions) && (limit < 0 || affected < int64(limit)) {
+			affected++
+			continue
+		}
+		kept = append(kept, row)
+	}
+	table.rows = kept
+	return affected, nil
+}
+
+func (p *memParser) showTables() (*memRows, error) {
+	pattern := p.next()
+	if !pattern.quoted {
+		return nil, errors.New("expected a pattern")
+	}
+	rows := &memRows{columns: []string{"table"}}
+	for _, name := range p.conn.db.tableNames() {
+		if pattern.text == "%" || pattern.text == name {
+			rows.values = append(rows.values, []driver.Value{name})
+		}
+	}
+	return rows, nil
+}
+
+// tableNames returns the names of the tables in order
+func (m *memDatabase) tableNames() []string {
+	names := make([]string, 0, len(m.tables))
+	for name := range m.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// information returns information_schema.TABLES for the database
+func (m *memDatabase) information() *memTable {
+	table := &memTable{}
+	for _, name := range m.tableNames() {
+		table.rows = append(table.rows, map[string]driver.Value{"table_schema": m.name, "table_name": name})
+	}
+	return table
+}
+
+func (p *memParser) selectRows() (*memRows, error) {
+	var selected []memExpr
+	var columns []string
+	count := false
+	for {
+		switch {
+		case p.accept("COUNT", "(", "*", ")"):
+			count = true
+			columns = append(columns, "count")
+		default:
+			start := p.peek()
+			value, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			selected = append(selected, value)
+			columns = append(columns, strings.ToLower(start))
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	if !p.accept("FROM") {
+		row := make([]driver.Value, len(selected))
+		for i, value := range selected {
+			row[i] = value.eval(nil, nil)
+		}
+		return &memRows{columns: columns, values: [][]driver.Value{row}}, nil
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	table := p.conn.db.information()
+	if !strings.EqualFold(name, "information_schema.tables") {
+		if table, err = p.table(name); err != nil {
+			return nil, err
+		}
+	}
+	conditions, err := p.where()
+	if err != nil {
+		return nil, err
+	}
+	var found []map[string]driver.Value
+	for _, row := range table.rows {
+		if matches(row, conditions) {
+			found = append(found, row)
+		}
+	}
+
+	if p.accept("ORDER", "BY") {
+		column, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		column = strings.ToLower(column)
+		descending := p.accept("DESC")
+		if !descending {
+			p.accept("ASC")
+		}
+		sort.SliceStable(found, func(i, j int) bool {
+			if descending {
+				return compare(found[i][column], found[j][column]) > 0
+			}
+			return compare(found[i][column], found[j][column]) < 0
+		})
+	}
+	limit, err := p.limit()
+	if err != nil {
+		return nil, err
+	}
+	if limit >= 0 && limit < len(found) {
+		found = found[:limit]
+	}
+
+	rows := &memRows{columns: columns}
+	if count {
+		rows.values = [][]driver.Value{{int64(len(found))}}
+		return rows, nil
+	}
+	for _, row := range found {
+		values := make([]driver.Value, len(selected))
+		for i, value := range selected {
+			values[i] = value.eval(row, nil)
+		}
+		rows.values = append(rows.values, values)
+	}
+	return rows, nil
+}
@@ -0,0 +1,308 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// StepsForModule applies n migrations of a module when n > 0, or rolls back -n migrations when n < 0
+func StepsForModule(db *sql.DB, moduleName string, n int) error {
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return err
+	}
+	return stepModule(db, module, n, false)
+}
+
+// StepsAll moves every module n steps: up in dependency order, or down in reverse order when n < 0
+func StepsAll(db *sql.DB, n int) error {
+	modules, err := app.Ordered()
+	if err != nil {
+		return fmt.Errorf("failed to order modules: %v", err)
+	}
+	if n < 0 {
+		modules = reversed(modules)
+	}
+
+	for _, module := range modules {
+		if err := stepModule(db, module, n, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GotoForModule migrates a module up or down to the given version. Moving up stops before the
+// first pending contract migration, which waits for migrate contract.
+func GotoForModule(db *sql.DB, moduleName string, version uint) error {
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return err
+	}
+	if err := checkDrift(db, module); err != nil {
+		return err
+	}
+
+	moved, err := moveModule(db, module, Operation{Command: "goto", Version: version}, true)
+	if err != nil {
+		return fmt.Errorf("failed to migrate module %s to version %d: %v", moduleName, version, err)
+	}
+
+	if moved == 0 {
+		log.Printf("No migrations to run for module: %s", moduleName)
+	} else {
+		log.Printf("Module %s migrated %d step(s) towards version %d", moduleName, moved, version)
+	}
+	return nil
+}
+
+// GotoAll migrates every module to its latest migration at or before the given version.
+// Versions are timestamps, so this restores the whole schema to a point in time.
+// Modules moving down are handled first, in reverse dependency order.
+func GotoAll(db *sql.DB, version uint) error {
+	targets, err := targetsAt(db, version)
+	if err != nil {
+		return err
+	}
+
+	// Roll back dependents before the modules they depend on
+	for i := len(targets) - 1; i >= 0; i-- {
+		target := targets[i]
+		if target.current == nil || (target.version != nil && *target.version >= *target.current) {
+			continue
+		}
+		if err := gotoTarget(db, target); err != nil {
+			return err
+		}
+	}
+
+	// Then apply migrations in dependency order
+	for _, target := range targets {
+		if target.version == nil || (target.current != nil && *target.version <= *target.current) {
+			continue
+		}
+		if err := gotoTarget(db, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ForceForModule records a module as being at the given version and clears its dirty flag
+// without running any migration. A version of -1 marks the module as having nothing applied.
+// Given tenants, it forces the module in each tenant schema instead of db.
+func ForceForModule(db *sql.DB, moduleName string, version int, tenants ...string) error {
+	if len(tenants) > 0 {
+		return runTenants(tenants, func(db *sql.DB) error { return ForceForModule(db, moduleName, version) })
+	}
+
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return err
+	}
+	return forceModule(db, module, version)
+}
+
+// forceModule records a module as being at the given version and forgets the checksums of the
+// migrations after it, so they are checked afresh when they run again
+func forceModule(db *sql.DB, module app.Module, version int) error {
+	err := runOnModule(db, module, func(m *migrate.Migrate) error {
+		return m.Force(version)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to force module %s to version %d: %v", module.Name(), version, err)
+	}
+	if err := forgetChecksumsAbove(db, module.Name(), version); err != nil {
+		return fmt.Errorf("failed to remove checksums of module %s above version %d: %v", module.Name(), version, err)
+	}
+
+	log.Printf("Module %s forced to version %d", module.Name(), version)
+	return nil
+}
+
+// ForceAll forces every module to its latest migration at or before the given version,
+// in each tenant schema when tenants are given
+func ForceAll(db *sql.DB, version uint, tenants ...string) error {
+	if len(tenants) > 0 {
+		return runTenants(tenants, func(db *sql.DB) error { return ForceAll(db, version) })
+	}
+
+	targets, err := targetsAt(db, version)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		forced := -1
+		if target.version != nil {
+			forced = int(*target.version)
+		}
+		if err := ForceForModule(db, target.module.Name(), forced); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moduleTarget pairs a module's current version with the version it should be moved to
+type moduleTarget struct {
+	module  app.Module
+	current *uint
+	version *uint
+}
+
+// targetsAt resolves, for every module, the latest migration at or before the given version
+func targetsAt(db *sql.DB, version uint) ([]moduleTarget, error) {
+	modules, err := app.Ordered()
+	if err != nil {
+		return nil, fmt.Errorf("failed to order modules: %v", err)
+	}
+
+	targets := make([]moduleTarget, 0, len(modules))
+	for _, module := range modules {
+		current, dirty, err := readVersion(db, VersionTable(module.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read version for module %s: %v", module.Name(), err)
+		}
+		if dirty {
+			return nil, fmt.Errorf("module %s is dirty at version %s; fix it with force first", module.Name(), formatVersion(current))
+		}
+
+		target, err := targetAt(module, current, version)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// targetAt resolves a module's latest migration at or before the given version; the target
+// version is nil when the module has nothing that old and should be rolled back completely
+func targetAt(module app.Module, current *uint, version uint) (moduleTarget, error) {
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return moduleTarget{}, fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+
+	target := moduleTarget{module: module, current: current}
+	for _, file := range files {
+		if file.Version <= version {
+			v := file.Version
+			target.version = &v
+		}
+	}
+	return target, nil
+}
+
+// gotoTarget moves a module to its resolved target, rolling everything back when it has none
+func gotoTarget(db *sql.DB, target moduleTarget) error {
+	if target.version == nil {
+		return RunForModule(db, target.module.Name(), "down")
+	}
+	return GotoForModule(db, target.module.Name(), *target.version)
+}
+
+// stepModule moves a single module n steps, treating "nothing left to run" as a no-op. Unless
+// includeContract is set, moving up stops before the first pending contract migration.
+func stepModule(db *sql.DB, module app.Module, n int, includeContract bool) error {
+	if n > 0 {
+		if err := checkDrift(db, module); err != nil {
+			return err
+		}
+	}
+
+	op := Operation{Command: "steps", Steps: n, IncludeContract: includeContract}
+	moved, err := moveModule(db, module, op, false)
+	switch {
+	case err != nil:
+		return fmt.Errorf("failed to migrate module %s %d step(s): %v", module.Name(), n, err)
+	case moved == 0:
+		log.Printf("No migrations to run for module: %s", module.Name())
+	case moved < abs(n):
+		log.Printf("Module %s migrated %d step(s), %d short of %d", module.Name(), moved, abs(n)-moved, abs(n))
+	default:
+		log.Printf("Module %s migrated %d step(s)", module.Name(), moved)
+	}
+	return nil
+}
+
+// moveModule runs an operation on a module, moving it to the version moveTarget resolves so that
+// it runs exactly what Plan lists, and returns the number of migrations run or rolled back
+func moveModule(db *sql.DB, module app.Module, op Operation, exactVersion bool) (int, error) {
+	current, dirty, err := readVersion(db, VersionTable(module.Name()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read version: %v", err)
+	}
+	if dirty {
+		return 0, fmt.Errorf("module is dirty at version %s; fix it with force first", formatVersion(current))
+	}
+	ups, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list migration files: %v", err)
+	}
+
+	position, target, held, err := moveTarget(module, ups, current, op, exactVersion)
+	if err != nil {
+		return 0, err
+	}
+	if held != "" {
+		log.Printf("Module %s: contract migration %s is held; run migrate contract once every instance is upgraded", module.Name(), held)
+	}
+	if target == position {
+		return 0, nil
+	}
+
+	err = runOnModule(db, module, func(m *migrate.Migrate) error {
+		if target == -1 {
+			return m.Down()
+		}
+		return m.Migrate(ups[target].Version)
+	})
+	if err != nil && err != migrate.ErrNoChange {
+		return 0, err
+	}
+	return abs(target - position), nil
+}
+
+// abs returns the absolute value of n
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// runOnModule opens a migrate instance for a module, passes it to fn and closes it afterwards
+func runOnModule(db *sql.DB, module app.Module, fn func(m *migrate.Migrate) error) error {
+	m, err := newMigrate(db, module)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	return fn(m)
+}
+
+// reversed returns a copy of modules in reverse order
+func reversed(modules []app.Module) []app.Module {
+	list := make([]app.Module, len(modules))
+	for i, module := range modules {
+		list[len(modules)-1-i] = module
+	}
+	return list
+}
+
+// formatVersion renders an optional version for messages
+func formatVersion(version *uint) string {
+	if version == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%d", *version)
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestTargetAt_MapsTimestampToEachModule(t *testing.T) {
+	users := testModule{name: "goto_users_test", files: fstest.MapFS{
+		"20250101000000_create_users.up.sql":  {Data: []byte("CREATE TABLE users (id INT);")},
+		"20250301000000_add_full_name.up.sql": {Data: []byte("ALTER TABLE users ADD COLUMN full_name VARCHAR(255);")},
+		"20250501000000_add_nickname.up.sql":  {Data: []byte("ALTER TABLE users ADD COLUMN nickname VARCHAR(255);")},
+	}}
+	billing := testModule{name: "goto_billing_test", files: fstest.MapFS{
+		"20250401000000_create_invoices.up.sql": {Data: []byte("CREATE TABLE invoices (id INT);")},
+	}}
+	empty := testModule{name: "goto_empty_test", files: fstest.MapFS{}}
+	applied := uint(20250501000000)
+
+	tests := []struct {
+		name    string
+		module  testModule
+		version uint
+		want    string
+	}{
+		{"between migrations", users, 20250415000000, "20250301000000"},
+		{"exactly on a migration", users, 20250301000000, "20250301000000"},
+		{"after every migration", users, 20260101000000, "20250501000000"},
+		{"before every migration", billing, 20250315000000, "none"},
+		{"module without migrations", empty, 20250315000000, "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := targetAt(tt.module, &applied, tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := formatVersion(target.version); got != tt.want {
+				t.Errorf("Expected target %s, got %s", tt.want, got)
+			}
+			if target.current != &applied {
+				t.Error("Expected the current version to be kept")
+			}
+		})
+	}
+}
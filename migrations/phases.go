@@ -0,0 +1,142 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Migration phases. Expand migrations only add to the schema, so they can run before a deploy
+// while old instances are still serving; contract migrations remove what old instances rely on
+// and are held until every instance runs the new release. Untagged migrations are expand migrations.
+const (
+	PhaseExpand   = "expand"
+	PhaseContract = "contract"
+)
+
+// phaseTag matches the comment that tags a SQL migration with its phase, e.g. "-- phase: contract"
+var phaseTag = regexp.MustCompile(`(?im)^\s*--\s*phase:\s*(\S*)\s*$`)
+
+// ContractAll applies the held contract migrations, and everything after them, of every registered
+// module. Run it once every instance has been upgraded to the release that no longer needs what they remove.
+// Given tenants, it runs in each tenant schema instead of db.
+func ContractAll(db *sql.DB, tenants ...string) error {
+	if len(tenants) > 0 {
+		return runTenants(tenants, func(db *sql.DB) error { return ContractAll(db) })
+	}
+
+	modules, err := app.Ordered()
+	if err != nil {
+		return fmt.Errorf("failed to order modules: %v", err)
+	}
+
+	for _, module := range modules {
+		if err := applyMigrations(db, module, true); err != nil {
+			return fmt.Errorf("failed to apply contract migrations for module %s: %v", module.Name(), err)
+		}
+	}
+
+	log.Println("All contract migrations applied successfully!")
+	return nil
+}
+
+// ContractForModule applies the held contract migrations of a specific module, in each tenant schema when tenants are given
+func ContractForModule(db *sql.DB, moduleName string, tenants ...string) error {
+	if len(tenants) > 0 {
+		return runTenants(tenants, func(db *sql.DB) error { return ContractForModule(db, moduleName) })
+	}
+
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return err
+	}
+	if err := applyMigrations(db, module, true); err != nil {
+		return fmt.Errorf("failed to apply contract migrations for module %s: %v", moduleName, err)
+	}
+
+	log.Printf("Contract migrations applied successfully for module: %s", moduleName)
+	return nil
+}
+
+// migrationPhase returns the phase of a migration: the Phase of a Go migration,
+// or the phase comment of a SQL file, defaulting to expand
+func migrationPhase(module app.Module, m *source.Migration) (string, error) {
+	if isGoMigration(m) {
+		if migration, ok := goMigration(module.Name(), m.Version); ok && migration.Phase != "" {
+			return migration.Phase, nil
+		}
+		return PhaseExpand, nil
+	}
+
+	body, err := readMigration(module, m)
+	if err != nil {
+		return "", err
+	}
+	return parsePhase(body)
+}
+
+// parsePhase reads the phase comment of a SQL migration
+func parsePhase(body string) (string, error) {
+	match := phaseTag.FindStringSubmatch(body)
+	if match == nil {
+		return PhaseExpand, nil
+	}
+	switch phase := strings.ToLower(match[1]); phase {
+	case PhaseExpand, PhaseContract:
+		return phase, nil
+	default:
+		return "", fmt.Errorf("invalid migration phase %q; use %s or %s", match[1], PhaseExpand, PhaseContract)
+	}
+}
+
+// firstContract returns the index of the first pending contract migration among a module's
+// up migrations, or -1 when none is pending
+func firstContract(module app.Module, files []*source.Migration, current *uint) (int, error) {
+	for i, file := range files {
+		if current != nil && file.Version <= *current {
+			continue
+		}
+		phase, err := migrationPhase(module, file)
+		if err != nil {
+			return -1, fmt.Errorf("%s: %v", file.Raw, err)
+		}
+		if phase == PhaseContract {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// holdContracts clamps a move of a module up to the index target among its up migrations so that
+// it stops before the first pending contract migration, which only migrate contract applies. It
+// returns the index the move may end at and the migration held, empty when the move crosses none.
+func holdContracts(module app.Module, files []*source.Migration, current *uint, target int) (int, string, error) {
+	held, err := firstContract(module, files, current)
+	if err != nil || held == -1 || held > target {
+		return target, "", err
+	}
+	return held - 1, files[held].Raw, nil
+}
+
+// pendingContracts lists the pending contract migrations of a module
+func pendingContracts(module app.Module, files []*source.Migration, current *uint) ([]string, error) {
+	var pending []string
+	for _, file := range files {
+		if current != nil && file.Version <= *current {
+			continue
+		}
+		phase, err := migrationPhase(module, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file.Raw, err)
+		}
+		if phase == PhaseContract {
+			pending = append(pending, file.Raw)
+		}
+	}
+	return pending, nil
+}
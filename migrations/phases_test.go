@@ -0,0 +1,90 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+func TestFirstContract_HoldsPendingContractMigrations(t *testing.T) {
+	module := testModule{name: "phases_test", files: fstest.MapFS{
+		"1_add_full_name.up.sql":   {Data: []byte("-- phase: expand\nALTER TABLE users ADD COLUMN full_name VARCHAR(255);")},
+		"1_add_full_name.down.sql": {Data: []byte("ALTER TABLE users DROP COLUMN full_name;")},
+		"3_drop_name.up.sql":       {Data: []byte("-- phase: contract\nALTER TABLE users DROP COLUMN name;")},
+		"3_drop_name.down.sql":     {Data: []byte("ALTER TABLE users ADD COLUMN name VARCHAR(255);")},
+		"4_add_nickname.up.sql":    {Data: []byte("ALTER TABLE users ADD COLUMN nickname VARCHAR(255);")},
+		"4_add_nickname.down.sql":  {Data: []byte("ALTER TABLE users DROP COLUMN nickname;")},
+	}}
+	Register(GoMigration{Module: module.name, Version: 5, Name: "drop_legacy", Phase: PhaseContract, Up: func(ctx context.Context, conn *sql.Conn) error { return nil }})
+
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	held, err := firstContract(module, files, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if held != 1 {
+		t.Errorf("Expected 3_drop_name to be held, got index %d", held)
+	}
+
+	current := uint(3)
+	pending, err := pendingContracts(module, files, &current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0] != "5_drop_legacy.up.go" {
+		t.Errorf("Expected only the Go contract migration to be outstanding, got %v", pending)
+	}
+}
+
+func TestParsePhase_RejectsUnknownPhases(t *testing.T) {
+	if _, err := parsePhase("-- phase: migrate\nSELECT 1;"); err == nil {
+		t.Error("Expected an error for an unknown phase")
+	}
+}
+
+func TestMoveTarget_StepsAndGotoStopBeforeContract(t *testing.T) {
+	module := testModule{name: "phases_move_test", files: fstest.MapFS{
+		"1_add_full_name.up.sql":   {Data: []byte("ALTER TABLE users ADD COLUMN full_name VARCHAR(255);")},
+		"2_backfill.up.sql":        {Data: []byte("UPDATE users SET full_name = name;")},
+		"3_drop_name.up.sql":       {Data: []byte("-- phase: contract\nALTER TABLE users DROP COLUMN name;")},
+		"4_add_nickname.up.sql":    {Data: []byte("ALTER TABLE users ADD COLUMN nickname VARCHAR(255);")},
+		"1_add_full_name.down.sql": {Data: []byte("ALTER TABLE users DROP COLUMN full_name;")},
+	}}
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := uint(1)
+
+	tests := []struct {
+		name   string
+		op     Operation
+		target int
+		held   string
+	}{
+		{"steps crossing the contract", Operation{Command: "steps", Steps: 3}, 1, "3_drop_name.up.sql"},
+		{"steps stopping before the contract", Operation{Command: "steps", Steps: 1}, 1, ""},
+		{"goto past the contract", Operation{Command: "goto", Version: 4}, 1, "3_drop_name.up.sql"},
+		{"goto including contracts", Operation{Command: "goto", Version: 4, IncludeContract: true}, 3, ""},
+		{"contract", Operation{Command: "contract"}, 3, ""},
+		{"steps rolling back", Operation{Command: "steps", Steps: -1}, -1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			position, target, held, err := moveTarget(module, files, &current, tt.op, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if position != 0 || target != tt.target || held != tt.held {
+				t.Errorf("Expected 0 -> %d holding %q, got %d -> %d holding %q", tt.target, tt.held, position, target, held)
+			}
+		})
+	}
+}
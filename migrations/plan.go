@@ -0,0 +1,177 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"database/sql"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// Operation describes a migration command so it can be planned without running it
+type Operation struct {
+	Command string // "up", "contract", "down", "steps" or "goto"
+	Steps   int    // number of steps for "steps"; negative values roll back
+	Version uint   // target version for "goto"
+	// IncludeContract lets "up", "steps" and "goto" run contract migrations instead of stopping
+	// before them; "contract" always does
+	IncludeContract bool
+}
+
+// PlannedMigration is a single migration file that an operation would run
+type PlannedMigration struct {
+	Module    string `json:"module"`
+	Version   uint   `json:"version"`
+	Direction string `json:"direction"`
+	File      string `json:"file"` // empty when the version has no file for this direction
+	SQL       string `json:"sql"`
+}
+
+// Plan returns, in execution order, the migrations an operation would run for one module
+// (or all modules when moduleName is empty). It only reads the database.
+func Plan(db *sql.DB, moduleName string, op Operation) ([]PlannedMigration, error) {
+	var modules []app.Module
+	if moduleName != "" {
+		module, err := app.Get(moduleName)
+		if err != nil {
+			return nil, err
+		}
+		modules = []app.Module{module}
+	} else {
+		var err error
+		if modules, err = app.Ordered(); err != nil {
+			return nil, fmt.Errorf("failed to order modules: %v", err)
+		}
+	}
+
+	var ups, downs []PlannedMigration
+	for _, module := range modules {
+		planned, err := planModule(db, module, op, moduleName != "")
+		if err != nil {
+			return nil, err
+		}
+		if len(planned) > 0 && planned[0].Direction == string(source.Down) {
+			downs = append(planned, downs...)
+		} else {
+			ups = append(ups, planned...)
+		}
+	}
+
+	// Roll back dependents before their dependencies, then apply in dependency order
+	return append(downs, ups...), nil
+}
+
+// planModule resolves the target version of an operation for one module and lists the files between
+func planModule(db *sql.DB, module app.Module, op Operation, exactVersion bool) ([]PlannedMigration, error) {
+	current, dirty, err := readVersion(db, VersionTable(module.Name()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version for module %s: %v", module.Name(), err)
+	}
+	if dirty {
+		return nil, fmt.Errorf("module %s is dirty at version %s; fix it with force first", module.Name(), formatVersion(current))
+	}
+
+	ups, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+	return planMigrations(module, ups, current, op, exactVersion)
+}
+
+// planMigrations lists the files an operation runs on a module at the current version, in order
+func planMigrations(module app.Module, ups []*source.Migration, current *uint, op Operation, exactVersion bool) ([]PlannedMigration, error) {
+	position, target, _, err := moveTarget(module, ups, current, op, exactVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var planned []PlannedMigration
+	for i := position + 1; i <= target; i++ {
+		step, err := planStep(module, ups[i].Version, source.Up)
+		if err != nil {
+			return nil, err
+		}
+		planned = append(planned, step)
+	}
+	for i := position; i > target; i-- {
+		step, err := planStep(module, ups[i].Version, source.Down)
+		if err != nil {
+			return nil, err
+		}
+		planned = append(planned, step)
+	}
+	return planned, nil
+}
+
+// moveTarget resolves where an operation takes a module. It returns the index among the module's
+// up migrations of the current version and of the version the module ends at, -1 meaning nothing
+// applied. Moving up stops before the first pending contract migration unless the operation
+// includes contract migrations; held is the migration that stopped it, empty when none did.
+// Plan and the runner both go through it, so a plan lists exactly what would run.
+func moveTarget(module app.Module, ups []*source.Migration, current *uint, op Operation, exactVersion bool) (position, target int, held string, err error) {
+	position = -1
+	if current != nil {
+		for i, file := range ups {
+			if file.Version == *current {
+				position = i
+			}
+		}
+		if position == -1 {
+			return 0, 0, "", fmt.Errorf("module %s is at version %d, which has no migration file", module.Name(), *current)
+		}
+	}
+
+	includeContract := op.IncludeContract
+	switch op.Command {
+	case "up":
+		target = len(ups) - 1
+	case "contract":
+		target = len(ups) - 1
+		includeContract = true
+	case "down":
+		target = -1
+	case "steps":
+		target = min(max(position+op.Steps, -1), len(ups)-1)
+	case "goto":
+		target = -1
+		found := false
+		for i, file := range ups {
+			if file.Version <= op.Version {
+				target = i
+			}
+			found = found || file.Version == op.Version
+		}
+		if exactVersion && !found {
+			return 0, 0, "", fmt.Errorf("module %s has no migration with version %d", module.Name(), op.Version)
+		}
+	default:
+		return 0, 0, "", fmt.Errorf("cannot plan migration command: %s", op.Command)
+	}
+
+	if target > position && !includeContract {
+		target, held, err = holdContracts(module, ups, current, target)
+	}
+	return position, target, held, err
+}
+
+// planStep loads the file and SQL of one migration version in one direction
+func planStep(module app.Module, version uint, direction source.Direction) (PlannedMigration, error) {
+	step := PlannedMigration{Module: module.Name(), Version: version, Direction: string(direction)}
+
+	files, err := moduleMigrations(module, direction)
+	if err != nil {
+		return step, fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+	for _, file := range files {
+		if file.Version != version {
+			continue
+		}
+		content, err := readMigration(module, file)
+		if err != nil {
+			return step, fmt.Errorf("failed to read migration %s: %v", file.Raw, err)
+		}
+		step.File = file.Raw
+		step.SQL = content
+	}
+	return step, nil
+}
@@ -0,0 +1,86 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+func TestPlanMigrations(t *testing.T) {
+	module := testModule{name: "plan_test", files: fstest.MapFS{
+		"1_create_users.up.sql":    {Data: []byte("CREATE TABLE users (id INT);")},
+		"1_create_users.down.sql":  {Data: []byte("DROP TABLE users;")},
+		"2_add_full_name.up.sql":   {Data: []byte("ALTER TABLE users ADD COLUMN full_name VARCHAR(255);")},
+		"2_add_full_name.down.sql": {Data: []byte("ALTER TABLE users DROP COLUMN full_name;")},
+		"3_drop_name.up.sql":       {Data: []byte("-- phase: contract\nALTER TABLE users DROP COLUMN name;")},
+		"3_drop_name.down.sql":     {Data: []byte("ALTER TABLE users ADD COLUMN name VARCHAR(255);")},
+		"4_add_nickname.up.sql":    {Data: []byte("ALTER TABLE users ADD COLUMN nickname VARCHAR(255);")},
+		"4_add_nickname.down.sql":  {Data: []byte("ALTER TABLE users DROP COLUMN nickname;")},
+	}}
+	ups, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := func(v uint) *uint { return &v }
+
+	tests := []struct {
+		name    string
+		current *uint
+		op      Operation
+		want    string // planned files, in order
+	}{
+		{"up holds the contract", nil, Operation{Command: "up"}, "1_create_users.up.sql 2_add_full_name.up.sql"},
+		{"up with the contract pending", version(2), Operation{Command: "up"}, ""},
+		{"contract", version(2), Operation{Command: "contract"}, "3_drop_name.up.sql 4_add_nickname.up.sql"},
+		{"up after the contract", version(3), Operation{Command: "up"}, "4_add_nickname.up.sql"},
+		{"down", version(2), Operation{Command: "down"}, "2_add_full_name.down.sql 1_create_users.down.sql"},
+		{"steps", nil, Operation{Command: "steps", Steps: 1}, "1_create_users.up.sql"},
+		{"steps crossing the contract", version(1), Operation{Command: "steps", Steps: 3}, "2_add_full_name.up.sql"},
+		{"steps back", version(4), Operation{Command: "steps", Steps: -2}, "4_add_nickname.down.sql 3_drop_name.down.sql"},
+		{"steps back past the start", version(1), Operation{Command: "steps", Steps: -5}, "1_create_users.down.sql"},
+		{"goto up", nil, Operation{Command: "goto", Version: 2}, "1_create_users.up.sql 2_add_full_name.up.sql"},
+		{"goto across the contract", version(2), Operation{Command: "goto", Version: 4}, ""},
+		{"goto down", version(4), Operation{Command: "goto", Version: 2}, "4_add_nickname.down.sql 3_drop_name.down.sql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planned, err := planMigrations(module, ups, tt.current, tt.op, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, step := range planned {
+				if step.SQL == "" {
+					t.Errorf("Expected %s to carry its SQL", step.File)
+				}
+				files = append(files, step.File)
+			}
+			if got := strings.Join(files, " "); got != tt.want {
+				t.Errorf("Expected plan %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPlanMigrations_RejectsUnknownVersions(t *testing.T) {
+	module := testModule{name: "plan_versions_test", files: fstest.MapFS{
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	}}
+	ups, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := uint(7)
+	for _, op := range []Operation{{Command: "up"}, {Command: "goto", Version: 7}} {
+		if _, err := planMigrations(module, ups, &current, op, true); err == nil {
+			t.Errorf("Expected an error planning %s from a version without a file", op.Command)
+		}
+	}
+	if _, err := planMigrations(module, ups, nil, Operation{Command: "goto", Version: 5}, true); err == nil {
+		t.Error("Expected an error for a goto to a version without a file")
+	}
+}
@@ -0,0 +1,127 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	// runContext cancels migration runs; set with UseContext
+	runContext = context.Background()
+	// statementTimeout bounds each SQL migration; zero means no limit
+	statementTimeout time.Duration
+	// lockTimeout bounds the wait for the migration lock and for table locks; zero keeps the defaults
+	lockTimeout time.Duration
+	// progressInterval is how often a running migration reports that it is still running
+	progressInterval = 10 * time.Second
+	// killGrace is how long an interrupted migration gets to stop once its statement is killed
+	killGrace = 30 * time.Second
+)
+
+// UseContext sets the context that interrupts migration runs when cancelled. No new migration
+// starts once it is done, and the statement of the running SQL migration is killed.
+func UseContext(ctx context.Context) {
+	runContext = ctx
+}
+
+// SetTimeouts bounds how long a single SQL migration may run and how long the runner waits for
+// the migration lock and for the table locks a statement needs. Zero leaves a limit unset.
+func SetTimeouts(statement, lock time.Duration) {
+	statementTimeout = statement
+	lockTimeout = lock
+}
+
+// progressLogger streams golang-migrate's log lines, prefixed with the module they belong to
+type progressLogger struct {
+	module string
+}
+
+// Printf logs a golang-migrate message
+func (l progressLogger) Printf(format string, v ...interface{}) {
+	log.Printf("Module %s: %s", l.module, strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+// Verbose keeps golang-migrate to one line per finished migration
+func (l progressLogger) Verbose() bool {
+	return false
+}
+
+// prepareConn applies the lock timeout to the connection migrations run on and returns its id
+func prepareConn(ctx context.Context, conn *sql.Conn) (int64, error) {
+	if lockTimeout > 0 {
+		seconds := max(int64(lockTimeout/time.Second), 1)
+		query := fmt.Sprintf("SET SESSION lock_wait_timeout = %d, SESSION innodb_lock_wait_timeout = %d", seconds, seconds)
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return 0, fmt.Errorf("failed to set lock timeout: %v", err)
+		}
+	}
+
+	var id int64
+	if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to read connection id: %v", err)
+	}
+	return id, nil
+}
+
+// execute runs a migration body while logging its progress. When the run is interrupted or the
+// statement timeout passes, the running statement is killed, Go migrations see their context
+// done, and the migration fails; one still running after killGrace is abandoned and the module's
+// version is marked dirty, since it may still change the schema.
+func (d *moduleDriver) execute(migration io.Reader) error {
+	file := "migration"
+	if d.step != nil {
+		file = d.file(d.step)
+	}
+	started := time.Now()
+	log.Printf("Module %s: running %s", d.module, file)
+
+	ctx, cancel := runContext, context.CancelFunc(func() {})
+	if statementTimeout > 0 {
+		ctx, cancel = context.WithTimeout(runContext, statementTimeout)
+	}
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- d.run(ctx, migration) }()
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-ticker.C:
+			log.Printf("Module %s: %s still running (%s elapsed)", d.module, file, time.Since(started).Round(time.Second))
+		case <-ctx.Done():
+			if _, err := d.db.Exec(fmt.Sprintf("KILL QUERY %d", d.connID)); err != nil {
+				log.Printf("Module %s: failed to kill the statement of %s: %v", d.module, file, err)
+			}
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(killGrace):
+				err = fmt.Errorf("still running %s after it was stopped", killGrace)
+				if dirtyErr := markDirty(d.db, d.module); dirtyErr != nil {
+					log.Printf("Module %s: failed to mark version %d dirty: %v", d.module, d.current, dirtyErr)
+				}
+				err = fmt.Errorf("%v; version %d is marked dirty, check the database and clear it with force before retrying", err, d.current)
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%s exceeded the statement timeout of %s: %v", file, statementTimeout, err)
+			}
+			return fmt.Errorf("%s was interrupted after %s: %v", file, time.Since(started).Round(time.Second), err)
+		}
+	}
+}
+
+// markDirty flags a module's version as dirty through the pool, as the migration connection may still be busy
+func markDirty(db *sql.DB, moduleName string) error {
+	_, err := db.Exec("UPDATE `" + VersionTable(moduleName) + "` SET dirty = 1")
+	return err
+}
@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// slowDriver is a database driver whose migrations take a while and then fail as if killed
+type slowDriver struct {
+	database.Driver
+}
+
+func (slowDriver) Run(migration io.Reader) error {
+	time.Sleep(100 * time.Millisecond)
+	return errors.New("Query execution was interrupted")
+}
+
+func TestExecute_StatementTimeoutKillsTheMigration(t *testing.T) {
+	db, _ := openMemDB(t)
+
+	SetTimeouts(10*time.Millisecond, 0)
+	defer SetTimeouts(0, 0)
+
+	d := &moduleDriver{Driver: slowDriver{}, db: db, module: "progress_test"}
+	err := d.execute(strings.NewReader("ALTER TABLE users ADD COLUMN phone VARCHAR(15);"))
+	if err == nil || !strings.Contains(err.Error(), "exceeded the statement timeout of 10ms") {
+		t.Errorf("Expected a statement timeout error, got %v", err)
+	}
+}
+
+func TestExecute_StopsGoMigrations(t *testing.T) {
+	db, _ := openMemDB(t)
+
+	module := testModule{name: "progress_go_test"}
+	Register(GoMigration{Module: module.name, Version: 1, Name: "honours_context", Up: func(ctx context.Context, conn *sql.Conn) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	Register(GoMigration{Module: module.name, Version: 2, Name: "ignores_context", Up: func(ctx context.Context, conn *sql.Conn) error {
+		time.Sleep(time.Second)
+		return nil
+	}})
+	src, err := newMemorySource(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetTimeouts(10*time.Millisecond, 0)
+	defer SetTimeouts(0, 0)
+	previousGrace := killGrace
+	killGrace = 50 * time.Millisecond
+	defer func() { killGrace = previousGrace }()
+
+	tests := []struct {
+		version uint
+		want    string
+	}{
+		{1, "context deadline exceeded"},
+		{2, "still running 50ms after it was stopped"},
+	}
+	for _, tt := range tests {
+		setVersion(t, db, module.name, int64(tt.version))
+		d := &moduleDriver{Driver: slowDriver{}, db: db, module: module.name, source: src, current: int(tt.version),
+			step: &migrationStep{version: tt.version, direction: source.Up}}
+		started := time.Now()
+		err := d.execute(strings.NewReader(""))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected version %d to fail with %q, got %v", tt.version, tt.want, err)
+		}
+		if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+			t.Errorf("Expected version %d to be abandoned promptly, took %s", tt.version, elapsed)
+		}
+	}
+
+	// The abandoned migration may still change the schema, so its version is left dirty
+	if version, dirty, err := readVersion(db, VersionTable(module.name)); err != nil || !dirty {
+		t.Errorf("Expected version %s to be marked dirty, got dirty %t, %v", formatVersion(version), dirty, err)
+	}
+}
+
+func TestRun_GoMigrationsUseTheMigrationConnection(t *testing.T) {
+	module := testModule{name: "progress_conn_test", files: fstest.MapFS{}}
+	var used int64
+	Register(GoMigration{Module: module.name, Version: 1, Name: "connection_id", Up: func(ctx context.Context, conn *sql.Conn) error {
+		return conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&used)
+	}})
+	db, mem := openMemDB(t)
+
+	if err := runOnModule(db, module, func(m *migrate.Migrate) error { return m.Up() }); err != nil {
+		t.Fatal(err)
+	}
+	// golang-migrate takes its lock on the connection the runner dedicates to the module
+	if locked := mem.ranOn("GET_LOCK"); used == 0 || used != locked {
+		t.Errorf("Expected the Go migration to run on connection %d, which holds the migration lock, got %d", locked, used)
+	}
+}
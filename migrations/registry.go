@@ -1,88 +1,71 @@
 package migrations
 
 import (
+	"auto_verse/app"
 	"database/sql"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"sync"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source"
 )
 
-// MigrationFunc is a function that runs migrations for a module
-type MigrationFunc func(db *sql.DB) error
+// mu guards the registries of the migrations package
+var mu sync.Mutex
 
-var (
-	registry []MigrationFunc
-	mu       sync.Mutex
-)
-
-// Register adds a migration function to the registry
-func Register(migrationFunc MigrationFunc) {
-	mu.Lock()
-	defer mu.Unlock()
-	registry = append(registry, migrationFunc)
-}
+// RunAll runs the migrations of every registered module, dependencies first. Given tenants,
+// it runs them in each tenant schema instead of db, see UseTenants.
+func RunAll(db *sql.DB, tenants ...string) error {
+	if len(tenants) > 0 {
+		return runTenants(tenants, func(db *sql.DB) error { return RunAll(db) })
+	}
 
-// RunAll runs all registered migrations
-func RunAll(db *sql.DB) error {
-	// Get a list of all modules
-	modulesDir := "Modules"
-	modules, err := os.ReadDir(modulesDir)
+	modules, err := app.Ordered()
 	if err != nil {
-		return fmt.Errorf("failed to read modules directory: %v", err)
+		return fmt.Errorf("failed to order modules: %v", err)
 	}
 
 	// Iterate through each module and apply its migrations
 	for _, module := range modules {
-		if module.IsDir() {
-			moduleName := module.Name()
-			migrationsDir := filepath.Join(modulesDir, moduleName, "migrations")
-
-			// Check if the migrations directory exists
-			if _, err := os.Stat(migrationsDir); os.IsNotExist(err) {
-				log.Printf("No migrations directory found for module: %s. Skipping...", moduleName)
-				continue
-			}
-
-			// Check if there are any migration files
-			migrationFiles, err := filepath.Glob(filepath.Join(migrationsDir, "*.up.sql"))
-			if err != nil {
-				return fmt.Errorf("failed to list migration files for module %s: %v", moduleName, err)
-			}
-			if len(migrationFiles) == 0 {
-				log.Printf("No migration files found for module: %s. Skipping...", moduleName)
-				continue
-			}
-
-			// Apply migrations for this module
-			if err := applyMigrations(db, migrationsDir); err != nil {
-				return fmt.Errorf("failed to apply migrations for module %s: %v", moduleName, err)
-			}
-
-			log.Printf("Migrations applied successfully for module: %s", moduleName)
+		moduleName := module.Name()
+
+		// Check if there are any migration files
+		migrationFiles, err := moduleMigrations(module, source.Up)
+		if err != nil {
+			return fmt.Errorf("failed to list migration files for module %s: %v", moduleName, err)
+		}
+		if len(migrationFiles) == 0 {
+			log.Printf("No migration files found for module: %s. Skipping...", moduleName)
+			continue
 		}
+
+		// Apply migrations for this module
+		if err := applyMigrations(db, module, false); err != nil {
+			return fmt.Errorf("failed to apply migrations for module %s: %v", moduleName, err)
+		}
+
+		log.Printf("Migrations applied successfully for module: %s", moduleName)
 	}
 
 	log.Println("All migrations applied successfully!")
 	return nil
 }
 
-// RunForModule runs migrations for a specific module
-func RunForModule(db *sql.DB, moduleName, direction string) error {
-	migrationsDir := filepath.Join("Modules", moduleName, "migrations")
+// RunForModule runs migrations for a specific module, in each tenant schema when tenants are given
+func RunForModule(db *sql.DB, moduleName, direction string, tenants ...string) error {
+	if len(tenants) > 0 {
+		return runTenants(tenants, func(db *sql.DB) error { return RunForModule(db, moduleName, direction) })
+	}
 
-	// Check if the migrations directory exists
-	if _, err := os.Stat(migrationsDir); os.IsNotExist(err) {
-		return fmt.Errorf("no migrations directory found for module: %s", moduleName)
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return err
 	}
 
 	// Check if there are any migration files
-	migrationFiles, err := filepath.Glob(filepath.Join(migrationsDir, "*."+direction+".sql"))
+	migrationFiles, err := moduleMigrations(module, source.Direction(direction))
 	if err != nil {
 		return fmt.Errorf("failed to list migration files for module %s: %v", moduleName, err)
 	}
@@ -93,11 +76,11 @@ func RunForModule(db *sql.DB, moduleName, direction string) error {
 	// Apply or rollback migrations for this module
 	switch direction {
 	case "up":
-		if err := applyMigrations(db, migrationsDir); err != nil {
+		if err := applyMigrations(db, module, false); err != nil {
 			return fmt.Errorf("failed to apply migrations for module %s: %v", moduleName, err)
 		}
 	case "down":
-		if err := rollbackMigrations(db, migrationsDir); err != nil {
+		if err := rollbackMigrations(db, module); err != nil {
 			return fmt.Errorf("failed to rollback migrations for module %s: %v", moduleName, err)
 		}
 	default:
@@ -108,88 +91,124 @@ func RunForModule(db *sql.DB, moduleName, direction string) error {
 	return nil
 }
 
-// RollbackAll rolls back all migrations (down)
-func RollbackAll(db *sql.DB) error {
-	// Get a list of all modules
-	modulesDir := "Modules"
-	modules, err := os.ReadDir(modulesDir)
+// RollbackAll rolls back the migrations of every registered module (down), dependents first,
+// in each tenant schema when tenants are given
+func RollbackAll(db *sql.DB, tenants ...string) error {
+	if len(tenants) > 0 {
+		return runTenants(tenants, func(db *sql.DB) error { return RollbackAll(db) })
+	}
+
+	modules, err := app.Ordered()
 	if err != nil {
-		return fmt.Errorf("failed to read modules directory: %v", err)
+		return fmt.Errorf("failed to order modules: %v", err)
 	}
 
-	// Iterate through each module and rollback its migrations
-	for _, module := range modules {
-		if module.IsDir() {
-			moduleName := module.Name()
-			migrationsDir := filepath.Join(modulesDir, moduleName, "migrations")
-
-			// Check if the migrations directory exists
-			if _, err := os.Stat(migrationsDir); os.IsNotExist(err) {
-				log.Printf("No migrations directory found for module: %s. Skipping...", moduleName)
-				continue
-			}
-
-			// Check if there are any migration files
-			migrationFiles, err := filepath.Glob(filepath.Join(migrationsDir, "*.down.sql"))
-			if err != nil {
-				return fmt.Errorf("failed to list migration files for module %s: %v", moduleName, err)
-			}
-			if len(migrationFiles) == 0 {
-				log.Printf("No migration files found for module: %s. Skipping...", moduleName)
-				continue
-			}
-
-			// Rollback migrations for this module
-			if err := rollbackMigrations(db, migrationsDir); err != nil {
-				return fmt.Errorf("failed to rollback migrations for module %s: %v", moduleName, err)
-			}
-
-			log.Printf("Migrations rolled back successfully for module: %s", moduleName)
+	// Iterate through each module in reverse dependency order and rollback its migrations
+	for i := len(modules) - 1; i >= 0; i-- {
+		module := modules[i]
+		moduleName := module.Name()
+
+		// Check if there are any migration files
+		migrationFiles, err := moduleMigrations(module, source.Down)
+		if err != nil {
+			return fmt.Errorf("failed to list migration files for module %s: %v", moduleName, err)
+		}
+		if len(migrationFiles) == 0 {
+			log.Printf("No migration files found for module: %s. Skipping...", moduleName)
+			continue
 		}
+
+		// Rollback migrations for this module
+		if err := rollbackMigrations(db, module); err != nil {
+			return fmt.Errorf("failed to rollback migrations for module %s: %v", moduleName, err)
+		}
+
+		log.Printf("Migrations rolled back successfully for module: %s", moduleName)
 	}
 
 	log.Println("All migrations rolled back successfully!")
 	return nil
 }
 
-// applyMigrations applies migrations for a specific module
-func applyMigrations(db *sql.DB, migrationsDir string) error {
-	driver, err := mysql.WithInstance(db, &mysql.Config{})
+// VersionTable returns the name of the table that tracks the applied version of a module
+func VersionTable(moduleName string) string {
+	return "schema_migrations_" + moduleName
+}
+
+// newMigrate creates a migrate instance that reads the module's migrations from memory
+// and tracks its version in the module's own table
+func newMigrate(db *sql.DB, module app.Module) (*migrate.Migrate, error) {
+	if dialect.Name != "mysql" {
+		return nil, fmt.Errorf("the migration runner supports mysql only, not %s; apply %s migrations with your database's own tooling", dialect.Name, dialect.Name)
+	}
+	src, err := newMemorySource(module)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %v", err)
+	}
+
+	// Use a dedicated connection so closing the migrate instance leaves the pool open
+	conn, err := db.Conn(runContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %v", err)
+	}
+	connID, err := prepareConn(runContext, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	mysqlDriver, err := mysql.WithConnection(runContext, conn, &mysql.Config{
+		MigrationsTable: VersionTable(module.Name()),
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create migration driver: %v", err)
+	}
+
+	driver, err := newModuleDriver(db, conn, module.Name(), mysqlDriver, src, connID)
 	if err != nil {
-		return fmt.Errorf("failed to create migration driver: %v", err)
+		mysqlDriver.Close()
+		return nil, fmt.Errorf("failed to create migration driver: %v", err)
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+migrationsDir, // Path to migration files
-		"mysql",                 // Database driver
-		driver,                  // Database instance
+	m, err := migrate.NewWithInstance(
+		"memory", // In-memory migration source
+		src,      // Source instance
+		"mysql",  // Database driver
+		driver,   // Database instance
 	)
 	if err != nil {
-		return fmt.Errorf("failed to initialize migrate instance: %v", err)
+		driver.Close()
+		return nil, fmt.Errorf("failed to initialize migrate instance: %v", err)
+	}
+	m.Log = progressLogger{module: module.Name()}
+	if lockTimeout > 0 {
+		m.LockTimeout = lockTimeout
 	}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("failed to apply migrations (up): %v", err)
+	return m, nil
+}
+
+// applyMigrations applies migrations for a specific module. Unless includeContract is set,
+// it stops before the first pending contract migration, which waits for migrate contract.
+func applyMigrations(db *sql.DB, module app.Module, includeContract bool) error {
+	if err := checkDrift(db, module); err != nil {
+		return err
 	}
 
+	if _, err := moveModule(db, module, Operation{Command: "up", IncludeContract: includeContract}, false); err != nil {
+		return fmt.Errorf("failed to apply migrations (up): %v", err)
+	}
 	return nil
 }
 
 // rollbackMigrations rolls back migrations for a specific module
-func rollbackMigrations(db *sql.DB, migrationsDir string) error {
-	driver, err := mysql.WithInstance(db, &mysql.Config{})
-	if err != nil {
-		return fmt.Errorf("failed to create migration driver: %v", err)
-	}
-
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+migrationsDir, // Path to migration files
-		"mysql",                 // Database driver
-		driver,                  // Database instance
-	)
+func rollbackMigrations(db *sql.DB, module app.Module) error {
+	m, err := newMigrate(db, module)
 	if err != nil {
-		return fmt.Errorf("failed to initialize migrate instance: %v", err)
+		return err
 	}
+	defer m.Close()
 
 	if err := m.Down(); err != nil && err != migrate.ErrNoChange {
 		return fmt.Errorf("failed to rollback migrations (down): %v", err)
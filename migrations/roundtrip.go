@@ -0,0 +1,136 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"auto_verse/schema"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// VerifyRoundTrip checks that a module's down migrations undo its up migrations. On a database
+// holding nothing of the module, it applies the module's dependencies, then applies the module's
+// migrations one at a time, rolls them back one at a time and applies them again, comparing the
+// whole schema after every rollback and after the reapply with what it was on the way up.
+func VerifyRoundTrip(db *sql.DB, moduleName string) error {
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return err
+	}
+
+	current, _, err := readVersion(db, VersionTable(module.Name()))
+	if err != nil {
+		return fmt.Errorf("failed to read version for module %s: %v", module.Name(), err)
+	}
+	if current != nil {
+		return fmt.Errorf("module %s is already at version %d; verify round trips on an empty database", module.Name(), *current)
+	}
+
+	ups, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+	downs, err := moduleMigrations(module, source.Down)
+	if err != nil {
+		return fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+	reversible := make(map[uint]bool, len(downs))
+	for _, down := range downs {
+		reversible[down.Version] = true
+	}
+	for _, up := range ups {
+		if !reversible[up.Version] {
+			return fmt.Errorf("%s has no down migration", up.Raw)
+		}
+	}
+
+	dependencies, err := dependencyModules(module)
+	if err != nil {
+		return err
+	}
+	for _, dependency := range dependencies {
+		if err := applyMigrations(db, dependency, true); err != nil {
+			return fmt.Errorf("failed to apply dependency %s: %v", dependency.Name(), err)
+		}
+	}
+
+	// states[i] is the schema after the first i migrations
+	states := make([]string, 0, len(ups)+1)
+	state, err := schemaState(db)
+	if err != nil {
+		return err
+	}
+	states = append(states, state)
+	for _, up := range ups {
+		if err := stepModule(db, module, 1, true); err != nil {
+			return fmt.Errorf("failed to apply %s: %v", up.Raw, err)
+		}
+		if state, err = schemaState(db); err != nil {
+			return err
+		}
+		states = append(states, state)
+	}
+
+	for i := len(ups) - 1; i >= 0; i-- {
+		if err := stepModule(db, module, -1, true); err != nil {
+			return fmt.Errorf("failed to roll back %s: %v", ups[i].Raw, err)
+		}
+		if state, err = schemaState(db); err != nil {
+			return err
+		}
+		if changes := diffLines(splitLines(states[i]), splitLines(state)); len(changes) > 0 {
+			return fmt.Errorf("rolling back %s did not restore the schema:\n%s", ups[i].Raw, strings.Join(changes, "\n"))
+		}
+	}
+
+	if err := applyMigrations(db, module, true); err != nil {
+		return fmt.Errorf("failed to reapply migrations: %v", err)
+	}
+	if state, err = schemaState(db); err != nil {
+		return err
+	}
+	if changes := diffLines(splitLines(states[len(states)-1]), splitLines(state)); len(changes) > 0 {
+		return fmt.Errorf("reapplying the migrations of module %s produced a different schema:\n%s", module.Name(), strings.Join(changes, "\n"))
+	}
+	return nil
+}
+
+// schemaState renders every application table of the current database, leaving out bookkeeping tables
+func schemaState(db *sql.DB) (string, error) {
+	rows, err := db.Query("SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME")
+	if err != nil {
+		return "", fmt.Errorf("failed to list tables: %v", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return "", err
+		}
+		if !bookkeepingTable(name) {
+			names = append(names, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	tables, err := schema.Inspect(db, names)
+	if err != nil {
+		return "", err
+	}
+	rendered := make([]string, 0, len(tables))
+	for _, table := range tables {
+		rendered = append(rendered, schema.CreateTable(table))
+	}
+	return strings.Join(rendered, "\n"), nil
+}
+
+// bookkeepingTable reports whether a table belongs to the migration and seed runners
+func bookkeepingTable(name string) bool {
+	return strings.HasPrefix(name, "schema_migrations_") || name == ChecksumTable || name == HistoryTable || name == "schema_seeds"
+}
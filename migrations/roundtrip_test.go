@@ -0,0 +1,93 @@
+package migrations
+
+import (
+	_ "auto_verse/Modules" // Register every module so their migrations can be verified
+	"auto_verse/app"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// TestRoundTrip applies, rolls back and reapplies the migrations of every module. It needs an
+// empty MySQL database that it may wipe, given as a DSN in MIGRATIONS_TEST_DSN, for example
+// "root:secret@tcp(localhost:3306)/auto_verse_test?multiStatements=true&parseTime=true".
+func TestRoundTrip(t *testing.T) {
+	dsn := os.Getenv("MIGRATIONS_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MIGRATIONS_TEST_DSN is not set")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	modules, err := app.Ordered()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, module := range modules {
+		t.Run(module.Name(), func(t *testing.T) {
+			resetDatabase(t, db)
+			if err := VerifyRoundTrip(db, module.Name()); err != nil {
+				t.Fatal(err)
+			}
+
+			// Every step up, down and up again is in the history
+			ups, err := moduleMigrations(module, source.Up)
+			if err != nil {
+				t.Fatal(err)
+			}
+			history, err := History(db, module.Name(), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != 3*len(ups) {
+				t.Errorf("Expected %d history entries, got %d", 3*len(ups), len(history))
+			}
+		})
+	}
+	resetDatabase(t, db)
+}
+
+// resetDatabase drops every table of the test database
+func resetDatabase(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	conn, err := db.Conn(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(t.Context(), "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	// Foreign key checks are per connection, so drop everything on the same one
+	if _, err := conn.ExecContext(t.Context(), "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if _, err := conn.ExecContext(t.Context(), "DROP TABLE `"+table+"`"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := conn.ExecContext(t.Context(), "SET FOREIGN_KEY_CHECKS = 1"); err != nil {
+		t.Fatal(err)
+	}
+}
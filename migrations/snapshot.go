@@ -0,0 +1,228 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"auto_verse/schema"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// SnapshotFile is the name of the schema snapshot kept next to each module's migrations
+const SnapshotFile = "schema.sql"
+
+var (
+	createTable = regexp.MustCompile("(?is)^CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?`?(\\w+)`?")
+	dropTable   = regexp.MustCompile("(?is)^DROP\\s+TABLE\\s+(?:IF\\s+EXISTS\\s+)?(.+)$")
+	renameTable = regexp.MustCompile("(?is)^(?:RENAME\\s+TABLE\\s+`?(\\w+)`?\\s+TO|ALTER\\s+TABLE\\s+`?(\\w+)`?\\s+RENAME\\s+(?:TO|AS))\\s+`?(\\w+)`?")
+)
+
+// SchemaDiff lists the lines that differ between a module's snapshot and the live database.
+// Lines starting with "-" are only in the snapshot, lines starting with "+" only in the database.
+type SchemaDiff struct {
+	Module   string   `json:"module"`
+	Snapshot string   `json:"snapshot"`
+	Changes  []string `json:"changes"`
+}
+
+// DumpSchemas regenerates the schema snapshot of a specific module, or of every module when
+// moduleName is empty. Modules whose source directory isn't present, as in a deployed binary,
+// are skipped. It returns the paths written.
+func DumpSchemas(db *sql.DB, moduleName string) ([]string, error) {
+	modules, err := selectModules(moduleName)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, module := range modules {
+		path := snapshotPath(module)
+		if _, err := os.Stat(filepath.Dir(path)); err != nil {
+			continue
+		}
+		snapshot, err := Snapshot(db, module.Name())
+		if err != nil {
+			return paths, err
+		}
+		if err := os.WriteFile(path, []byte(snapshot), 0644); err != nil {
+			return paths, fmt.Errorf("failed to write schema snapshot for module %s: %v", module.Name(), err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// Snapshot renders the tables, indexes and foreign keys a module's applied migrations created
+func Snapshot(db *sql.DB, moduleName string) (string, error) {
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return "", err
+	}
+
+	version, _, err := readVersion(db, VersionTable(module.Name()))
+	if err != nil {
+		return "", fmt.Errorf("failed to read version for module %s: %v", module.Name(), err)
+	}
+	names, err := moduleTables(module, version)
+	if err != nil {
+		return "", err
+	}
+	tables, err := schema.Inspect(db, names)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect tables of module %s: %v", module.Name(), err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Schema of the %s module at version %s.\n", module.Name(), formatVersion(version))
+	b.WriteString("-- Regenerated after migrations run; do not edit by hand.\n")
+	for _, table := range tables {
+		b.WriteString("\n" + schema.CreateTable(table) + "\n")
+	}
+	return b.String(), nil
+}
+
+// DiffSchemas compares the live database with the committed snapshot of a specific module,
+// or of every module when moduleName is empty, and returns the modules that differ
+func DiffSchemas(db *sql.DB, moduleName string) ([]SchemaDiff, error) {
+	modules, err := selectModules(moduleName)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []SchemaDiff
+	for _, module := range modules {
+		path := snapshotPath(module)
+		committed, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return diffs, fmt.Errorf("failed to read schema snapshot for module %s: %v", module.Name(), err)
+		}
+		live, err := Snapshot(db, module.Name())
+		if err != nil {
+			return diffs, err
+		}
+
+		changes := diffLines(splitLines(string(committed)), splitLines(live))
+		if len(changes) > 0 {
+			diffs = append(diffs, SchemaDiff{Module: module.Name(), Snapshot: path, Changes: changes})
+		}
+	}
+	return diffs, nil
+}
+
+// snapshotPath returns where a module's schema snapshot is kept
+func snapshotPath(module app.Module) string {
+	return filepath.Join(filepath.Dir(module.MigrationsDir()), SnapshotFile)
+}
+
+// selectModules returns the named module, or every module in dependency order when name is empty
+func selectModules(name string) ([]app.Module, error) {
+	if name != "" {
+		module, err := app.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return []app.Module{module}, nil
+	}
+	modules, err := app.Ordered()
+	if err != nil {
+		return nil, fmt.Errorf("failed to order modules: %v", err)
+	}
+	return modules, nil
+}
+
+// moduleTables replays the CREATE, DROP and RENAME TABLE statements of a module's SQL
+// migrations up to version and returns the tables that remain, in creation order
+func moduleTables(module app.Module, version *uint) ([]string, error) {
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+
+	var tables []string
+	remove := func(name string) {
+		for i, table := range tables {
+			if table == name {
+				tables = append(tables[:i], tables[i+1:]...)
+				return
+			}
+		}
+	}
+
+	for _, file := range files {
+		if version == nil || file.Version > *version || isGoMigration(file) {
+			continue
+		}
+		body, err := readMigration(module, file)
+		if err != nil {
+			return nil, err
+		}
+		for _, statement := range strings.Split(stripComments(body), ";") {
+			statement = strings.TrimSpace(statement)
+			if match := createTable.FindStringSubmatch(statement); match != nil {
+				remove(match[1])
+				tables = append(tables, match[1])
+			} else if match := dropTable.FindStringSubmatch(statement); match != nil {
+				for _, name := range strings.Split(match[1], ",") {
+					remove(strings.Trim(strings.TrimSpace(name), "`"))
+				}
+			} else if match := renameTable.FindStringSubmatch(statement); match != nil {
+				from := match[1] + match[2]
+				for i, table := range tables {
+					if table == from {
+						tables[i] = match[3]
+					}
+				}
+			}
+		}
+	}
+	return tables, nil
+}
+
+// splitLines splits text into lines, ignoring the trailing newline
+func splitLines(text string) []string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffLines returns a line diff of a and b, with "-" for lines only in a and "+" for lines only in b
+func diffLines(a, b []string) []string {
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var changes []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			changes = append(changes, "- "+a[i])
+			i++
+		default:
+			changes = append(changes, "+ "+b[j])
+			j++
+		}
+	}
+	return changes
+}
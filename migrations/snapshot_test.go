@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestModuleTables_ReplaysCreateDropAndRename(t *testing.T) {
+	module := testModule{name: "snapshot_test", files: fstest.MapFS{
+		"1_create.up.sql":   {Data: []byte("CREATE TABLE users (id INT);\nCREATE TABLE IF NOT EXISTS `tokens` (id INT); -- DROP TABLE users")},
+		"1_create.down.sql": {Data: []byte("DROP TABLE tokens, users;")},
+		"2_rename.up.sql":   {Data: []byte("ALTER TABLE tokens RENAME TO sessions;")},
+		"2_rename.down.sql": {Data: []byte("ALTER TABLE sessions RENAME TO tokens;")},
+		"3_drop.up.sql":     {Data: []byte("DROP TABLE IF EXISTS users;")},
+		"3_drop.down.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+	}}
+
+	cases := []struct {
+		version  uint
+		expected string
+	}{
+		{1, "users,tokens"},
+		{2, "users,sessions"},
+		{3, "sessions"},
+	}
+	for _, c := range cases {
+		version := c.version
+		tables, err := moduleTables(module, &version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(tables, ","); got != c.expected {
+			t.Errorf("At version %d expected tables %s, got %s", c.version, c.expected, got)
+		}
+	}
+
+	if tables, _ := moduleTables(module, nil); len(tables) != 0 {
+		t.Errorf("Expected no tables before any migration, got %v", tables)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	snapshot := []string{"CREATE TABLE users (", "    id INT,", "    name TEXT", ");"}
+	live := []string{"CREATE TABLE users (", "    id INT,", "    name VARCHAR(50),", "    email TEXT", ");"}
+
+	expected := []string{"-     name TEXT", "+     name VARCHAR(50),", "+     email TEXT"}
+	if got := diffLines(snapshot, live); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if got := diffLines(live, live); len(got) != 0 {
+		t.Errorf("Expected no changes, got %q", got)
+	}
+}
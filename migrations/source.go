@@ -0,0 +1,163 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// fromDisk makes the runner read migrations from Modules/<name>/migrations instead of the binary
+var fromDisk bool
+
+// UseDiskSource switches between the migrations embedded in the binary (the default)
+// and the migration files in the working directory, which is handy while writing them
+func UseDiskSource(enabled bool) {
+	fromDisk = enabled
+}
+
+// moduleFS returns the file system holding a module's migration files
+func moduleFS(module app.Module) fs.FS {
+	if fromDisk {
+		return os.DirFS(module.MigrationsDir())
+	}
+	if fsys := module.Migrations(); fsys != nil {
+		return fsys
+	}
+	return emptyFS{}
+}
+
+// emptyFS is used for modules that ship no migrations
+type emptyFS struct{}
+
+func (emptyFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// memorySource is a golang-migrate source driver serving migrations held in memory
+type memorySource struct {
+	migrations *source.Migrations
+	bodies     map[string][]byte
+}
+
+// newMemorySource reads every migration file of a module into memory alongside its Go migrations
+func newMemorySource(module app.Module) (*memorySource, error) {
+	src := &memorySource{
+		migrations: source.NewMigrations(),
+		bodies:     make(map[string][]byte),
+	}
+
+	for _, direction := range []source.Direction{source.Up, source.Down} {
+		files, err := moduleMigrations(module, direction)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			body, err := readMigration(module, file)
+			if err != nil {
+				return nil, err
+			}
+			if !src.migrations.Append(file) {
+				return nil, fmt.Errorf("duplicate migration version %d (%s)", file.Version, file.Raw)
+			}
+			src.bodies[file.Raw] = []byte(body)
+		}
+	}
+
+	return src, nil
+}
+
+// readMigration returns the SQL of a migration file, or a placeholder comment for a Go migration
+func readMigration(module app.Module, m *source.Migration) (string, error) {
+	if isGoMigration(m) {
+		return goMigrationBody(module.Name(), m), nil
+	}
+
+	body, err := fs.ReadFile(moduleFS(module), m.Raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migration %s: %v", m.Raw, err)
+	}
+	return string(body), nil
+}
+
+// Open is part of the source.Driver interface; memory sources are created with newMemorySource
+func (s *memorySource) Open(url string) (source.Driver, error) {
+	return nil, errors.New("memory source cannot be opened from a URL")
+}
+
+// Close is part of the source.Driver interface
+func (s *memorySource) Close() error {
+	return nil
+}
+
+// First returns the lowest migration version
+func (s *memorySource) First() (uint, error) {
+	if version, ok := s.migrations.First(); ok {
+		return version, nil
+	}
+	return 0, &fs.PathError{Op: "first", Path: "memory", Err: fs.ErrNotExist}
+}
+
+// Prev returns the version before the given one
+func (s *memorySource) Prev(version uint) (uint, error) {
+	if prev, ok := s.migrations.Prev(version); ok {
+		return prev, nil
+	}
+	return 0, &fs.PathError{Op: fmt.Sprintf("prev for version %d", version), Path: "memory", Err: fs.ErrNotExist}
+}
+
+// Next returns the version after the given one
+func (s *memorySource) Next(version uint) (uint, error) {
+	if next, ok := s.migrations.Next(version); ok {
+		return next, nil
+	}
+	return 0, &fs.PathError{Op: fmt.Sprintf("next for version %d", version), Path: "memory", Err: fs.ErrNotExist}
+}
+
+// ReadUp returns the body of the up migration for a version
+func (s *memorySource) ReadUp(version uint) (io.ReadCloser, string, error) {
+	if m, ok := s.migrations.Up(version); ok {
+		return io.NopCloser(bytes.NewReader(s.bodies[m.Raw])), m.Identifier, nil
+	}
+	return nil, "", &fs.PathError{Op: fmt.Sprintf("read up for version %d", version), Path: "memory", Err: fs.ErrNotExist}
+}
+
+// ReadDown returns the body of the down migration for a version
+func (s *memorySource) ReadDown(version uint) (io.ReadCloser, string, error) {
+	if m, ok := s.migrations.Down(version); ok {
+		return io.NopCloser(bytes.NewReader(s.bodies[m.Raw])), m.Identifier, nil
+	}
+	return nil, "", &fs.PathError{Op: fmt.Sprintf("read down for version %d", version), Path: "memory", Err: fs.ErrNotExist}
+}
+
+// listMigrations returns the parsed migration files of a file system for one direction, ordered by version
+func listMigrations(fsys fs.FS, direction source.Direction) ([]*source.Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*source.Migration
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m, err := source.Parse(entry.Name())
+		if err != nil || m.Direction != direction {
+			continue
+		}
+		files = append(files, m)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Version < files[j].Version })
+	return files, nil
+}
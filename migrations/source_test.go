@@ -0,0 +1,74 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+// testModule is a module whose migrations live in memory
+type testModule struct {
+	name  string
+	files fstest.MapFS
+}
+
+func (m testModule) Name() string                      { return m.name }
+func (m testModule) DependsOn() []string               { return nil }
+func (m testModule) RegisterRoutes(router *app.Router) {}
+func (m testModule) Migrations() fs.FS                 { return m.files }
+func (m testModule) MigrationsDir() string             { return "" }
+func (m testModule) Init(db *sql.DB) error             { return nil }
+func (m testModule) Shutdown() error                   { return nil }
+
+func TestMemorySource_InterleavesSQLAndGoMigrations(t *testing.T) {
+	module := testModule{name: "source_test", files: fstest.MapFS{
+		"3_third.up.sql":             {Data: []byte("CREATE TABLE c (id INT);")},
+		"3_third.down.sql":           {Data: []byte("DROP TABLE c;")},
+		"1_first.up.sql":             {Data: []byte("CREATE TABLE a (id INT);")},
+		"1_first.down.sql":           {Data: []byte("DROP TABLE a;")},
+		"0001_initial_migration.sql": {Data: []byte("-- not a migration")},
+	}}
+	Register(GoMigration{Module: module.name, Version: 2, Name: "backfill", Up: func(ctx context.Context, conn *sql.Conn) error { return nil }})
+
+	src, err := newMemorySource(module)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := src.First()
+	if err != nil || first != 1 {
+		t.Fatalf("First returned %v, %v; want 1", first, err)
+	}
+
+	next, err := src.Next(first)
+	if err != nil || next != 2 {
+		t.Fatalf("Next returned %v, %v; want the Go migration 2", next, err)
+	}
+
+	if _, _, err := src.ReadDown(2); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadDown of a Go migration without Down returned %v, want fs.ErrNotExist", err)
+	}
+
+	next, err = src.Next(next)
+	if err != nil || next != 3 {
+		t.Fatalf("Next returned %v, %v; want 3", next, err)
+	}
+
+	if _, err := src.Next(next); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Next past the last version returned %v, want fs.ErrNotExist", err)
+	}
+
+	body, identifier, err := src.ReadDown(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(body)
+	if identifier != "third" || string(content) != "DROP TABLE c;" {
+		t.Errorf("ReadDown returned %q, %q", identifier, content)
+	}
+}
@@ -0,0 +1,333 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"auto_verse/schema"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// BaselineName is the name of the migration that replaces squashed migrations.
+// Migrations older than a module's latest baseline are ignored by the runner.
+const BaselineName = "baseline"
+
+// SquashResult lists what a squash wrote and removed
+type SquashResult struct {
+	Module       string   `json:"module"`
+	Version      uint     `json:"version"`
+	Written      []string `json:"written"`
+	Removed      []string `json:"removed"`
+	GoMigrations []string `json:"go_migrations"` // registered Go migrations the baseline replaces; delete them by hand
+	Adopted      bool     `json:"adopted"`       // whether db was already past the baseline and was marked compatible
+}
+
+// Squash replaces a module's migrations up to and including version with a single baseline
+// migration at that version. The baseline is generated from the schema the migrations produce,
+// which is built by running the raw migrations of the module and its dependencies in the empty
+// scratch database. The squashed files are only removed once the baseline is in place.
+// Data changes made by the squashed migrations are not carried over. Databases already at or
+// past the version are compatible: db is marked so straight away, others on their next run.
+func Squash(db, scratch *sql.DB, moduleName string, version uint) (SquashResult, error) {
+	result := SquashResult{Module: moduleName, Version: version}
+
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return result, err
+	}
+	dir := module.MigrationsDir()
+	if _, err := os.Stat(dir); err != nil {
+		return result, fmt.Errorf("squash needs the module sources: %v", err)
+	}
+
+	// Squashing rewrites the files on disk, so build and verify from them rather than the binary
+	previous := fromDisk
+	UseDiskSource(true)
+	defer UseDiskSource(previous)
+
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return result, fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+	squashed := 0
+	found := false
+	for _, file := range files {
+		if file.Version <= version {
+			squashed++
+			found = found || file.Version == version
+		}
+	}
+	if !found {
+		return result, fmt.Errorf("module %s has no migration with version %d", module.Name(), version)
+	}
+	if squashed < 2 {
+		return result, fmt.Errorf("module %s has nothing to squash up to version %d", module.Name(), version)
+	}
+
+	up, down, err := baselineSQL(scratch, module, version)
+	if err != nil {
+		return result, err
+	}
+
+	// Write the baseline next to the files it replaces before touching them, so a failure
+	// leaves the module's migrations as they were
+	baseline := make(map[string]string)
+	defer func() {
+		for _, temp := range baseline {
+			os.Remove(temp)
+		}
+	}()
+	for _, file := range []struct {
+		direction source.Direction
+		body      string
+	}{{source.Up, up}, {source.Down, down}} {
+		path := filepath.Join(dir, fmt.Sprintf("%d_%s.%s.sql", version, BaselineName, file.direction))
+		temp, err := writeTemp(dir, file.body)
+		if err != nil {
+			return result, fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		baseline[path] = temp
+	}
+
+	current, dirty, err := readVersion(db, VersionTable(module.Name()))
+	if err != nil {
+		return result, fmt.Errorf("failed to read version for module %s: %v", module.Name(), err)
+	}
+	if dirty {
+		return result, fmt.Errorf("module %s is dirty at version %s; fix it with force before squashing", module.Name(), formatVersion(current))
+	}
+
+	for path, temp := range baseline {
+		if err := os.Rename(temp, path); err != nil {
+			return result, fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		delete(baseline, path)
+		result.Written = append(result.Written, path)
+	}
+	sort.Strings(result.Written)
+
+	// Only now remove the squashed files, keeping a baseline being squashed again, which was just replaced
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return result, err
+	}
+	for _, entry := range entries {
+		m, err := source.Parse(entry.Name())
+		path := filepath.Join(dir, entry.Name())
+		if err != nil || m.Version > version || slices.Contains(result.Written, path) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return result, fmt.Errorf("failed to remove %s: %v", path, err)
+		}
+		result.Removed = append(result.Removed, path)
+	}
+
+	for _, file := range files {
+		if isGoMigration(file) && file.Version <= version {
+			result.GoMigrations = append(result.GoMigrations, file.Raw)
+		}
+	}
+
+	if current != nil && *current >= version {
+		if err := adoptBaseline(db, module); err != nil {
+			return result, err
+		}
+		result.Adopted = true
+	}
+	return result, nil
+}
+
+// writeTemp writes body to a new hidden file in dir, which golang-migrate doesn't take for a migration
+func writeTemp(dir, body string) (string, error) {
+	file, err := os.CreateTemp(dir, ".squash-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_, err = file.WriteString(body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0644)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// baselineSQL builds the schema of the migrations up to version in the scratch database and renders
+// the module's tables as a migration
+func baselineSQL(scratch *sql.DB, module app.Module, version uint) (string, string, error) {
+	dependencies, err := dependencyModules(module)
+	if err != nil {
+		return "", "", err
+	}
+	for _, dependency := range dependencies {
+		if err := buildScratch(scratch, dependency, nil); err != nil {
+			return "", "", fmt.Errorf("failed to build the scratch schema: %v", err)
+		}
+	}
+	if err := buildScratch(scratch, module, &version); err != nil {
+		return "", "", fmt.Errorf("failed to build the scratch schema: %v", err)
+	}
+
+	names, err := moduleTables(module, &version)
+	if err != nil {
+		return "", "", err
+	}
+	tables, err := schema.Inspect(scratch, names)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to inspect the scratch schema: %v", err)
+	}
+
+	header := fmt.Sprintf("-- %d_%s.%%s.sql\n-- Baseline of the %s module, squashing every migration up to version %d.\n\n", version, BaselineName, module.Name(), version)
+	var up, down strings.Builder
+	fmt.Fprintf(&up, header, source.Up)
+	fmt.Fprintf(&down, header, source.Down)
+	for i, table := range tables {
+		up.WriteString(schema.CreateTable(table) + "\n\n")
+		fmt.Fprintf(&down, "DROP TABLE IF EXISTS %s;\n", tables[len(tables)-1-i].Name)
+	}
+	return strings.TrimRight(up.String(), "\n") + "\n", down.String(), nil
+}
+
+// buildScratch runs a module's up migrations, all of them or those up to version, straight from their
+// sources. Nothing is tracked in the scratch database: no version table, checksums or history, and
+// no guards or post hooks run.
+func buildScratch(scratch *sql.DB, module app.Module, version *uint) error {
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+	conn, err := scratch.Conn(runContext)
+	if err != nil {
+		return fmt.Errorf("failed to open scratch database connection: %v", err)
+	}
+	defer conn.Close()
+
+	for _, file := range files {
+		if version != nil && file.Version > *version {
+			break
+		}
+		if migration, ok := goMigration(module.Name(), file.Version); ok && isGoMigration(file) {
+			if err := migration.Up(runContext, conn); err != nil {
+				return fmt.Errorf("go migration %s failed: %v", file.Raw, err)
+			}
+			continue
+		}
+
+		body, err := readMigration(module, file)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(runContext, body); err != nil {
+			return fmt.Errorf("migration %s failed: %v", file.Raw, err)
+		}
+	}
+	return nil
+}
+
+// dependencyModules returns the modules a module depends on, directly or not, in migration order
+func dependencyModules(module app.Module) ([]app.Module, error) {
+	needed := make(map[string]bool)
+	var visit func(name string) error
+	visit = func(name string) error {
+		dependency, err := app.Get(name)
+		if err != nil {
+			return err
+		}
+		for _, next := range dependency.DependsOn() {
+			if !needed[next] {
+				needed[next] = true
+				if err := visit(next); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := visit(module.Name()); err != nil {
+		return nil, err
+	}
+
+	ordered, err := app.Ordered()
+	if err != nil {
+		return nil, fmt.Errorf("failed to order modules: %v", err)
+	}
+	var dependencies []app.Module
+	for _, candidate := range ordered {
+		if needed[candidate.Name()] {
+			dependencies = append(dependencies, candidate)
+		}
+	}
+	return dependencies, nil
+}
+
+// baselineVersion returns the version of the latest baseline among a module's migrations
+func baselineVersion(files []*source.Migration) (uint, bool) {
+	var version uint
+	found := false
+	for _, file := range files {
+		if file.Identifier == BaselineName && !isGoMigration(file) {
+			version, found = file.Version, true
+		}
+	}
+	return version, found
+}
+
+// adoptBaseline marks a database that ran the squashed migrations as compatible with the baseline:
+// checksums of the squashed versions are dropped and the baseline's replaces the one recorded at its version.
+// A database stopped between the first squashed migration and the baseline can't be adopted.
+func adoptBaseline(db *sql.DB, module app.Module) error {
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+	baseline, ok := baselineVersion(files)
+	if !ok {
+		return nil
+	}
+
+	current, _, err := readVersion(db, VersionTable(module.Name()))
+	if err != nil {
+		return fmt.Errorf("failed to read version for module %s: %v", module.Name(), err)
+	}
+	if current == nil {
+		return nil
+	}
+	if *current < baseline {
+		return fmt.Errorf("module %s is at version %d, inside the range squashed into baseline %d; "+
+			"migrate it to %d with a release that still has the squashed migrations", module.Name(), *current, baseline, baseline)
+	}
+
+	if err := ensureChecksumTable(db); err != nil {
+		return err
+	}
+	if _, err := db.Exec("DELETE FROM `"+ChecksumTable+"` WHERE module = ? AND version < ?", module.Name(), baseline); err != nil {
+		return fmt.Errorf("failed to adopt baseline for module %s: %v", module.Name(), err)
+	}
+	for _, file := range files {
+		if file.Version != baseline {
+			continue
+		}
+		body, err := readMigration(module, file)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec("UPDATE `"+ChecksumTable+"` SET file = ?, checksum = ? WHERE module = ? AND version = ? AND file <> ?",
+			file.Raw, checksum([]byte(body)), module.Name(), baseline, file.Raw)
+		if err != nil {
+			return fmt.Errorf("failed to adopt baseline for module %s: %v", module.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+func TestModuleMigrations_HidesMigrationsBeforeBaseline(t *testing.T) {
+	module := testModule{name: "squash_test", files: fstest.MapFS{
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"3_baseline.up.sql":       {Data: []byte("CREATE TABLE users (id INT, name TEXT);")},
+		"3_baseline.down.sql":     {Data: []byte("DROP TABLE IF EXISTS users;")},
+		"4_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;")},
+		"4_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP COLUMN email;")},
+	}}
+	Register(GoMigration{Module: module.name, Version: 2, Name: "backfill", Up: func(ctx context.Context, conn *sql.Conn) error { return nil }})
+
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var versions []uint
+	for _, file := range files {
+		versions = append(versions, file.Version)
+	}
+	if len(versions) != 2 || versions[0] != 3 || versions[1] != 4 {
+		t.Errorf("Expected only the baseline and later migrations (3, 4), got %v", versions)
+	}
+}
+
+func TestBuildScratch_RunsRawMigrationsWithoutTracking(t *testing.T) {
+	module := testModule{name: "squash_scratch_test", files: fstest.MapFS{
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);\nCREATE TABLE roles (id INT);")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE roles;\nDROP TABLE users;")},
+		"3_add_posts.up.sql":      {Data: []byte("CREATE TABLE posts (id INT);")},
+		"3_add_posts.down.sql":    {Data: []byte("DROP TABLE posts;")},
+	}}
+	Register(GoMigration{Module: module.name, Version: 2, Name: "create_audit", Up: func(ctx context.Context, conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, "CREATE TABLE audit (id INT)")
+		return err
+	}})
+	RegisterHook(Hook{Module: module.name, Version: 1, Guard: func(ctx context.Context, conn *sql.Conn) error {
+		return errors.New("guards must not run in the scratch database")
+	}})
+
+	scratch, mem := openMemDB(t)
+	version := uint(2)
+	if err := buildScratch(scratch, module, &version); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{"users", "roles", "audit"} {
+		if !mem.hasTable(table) {
+			t.Errorf("Expected the scratch database to have table %s", table)
+		}
+	}
+	for _, table := range []string{"posts", VersionTable(module.name), ChecksumTable, HistoryTable} {
+		if mem.hasTable(table) {
+			t.Errorf("Expected the scratch database not to have table %s", table)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"auto_verse/app"
+	"database/sql"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// ModuleStatus describes the migration state of a module
+type ModuleStatus struct {
+	Module  string   `json:"module"`
+	Version *uint    `json:"version"` // nil when no migration has been applied
+	Dirty   bool     `json:"dirty"`
+	Pending []string `json:"pending"`
+	// Contract lists the pending contract migrations, held by migrate up until migrate contract runs
+	Contract []string `json:"contract"`
+}
+
+// Status reports the applied version, dirty flag and pending files of every registered module
+func Status(db *sql.DB) ([]ModuleStatus, error) {
+	modules, err := app.Ordered()
+	if err != nil {
+		return nil, fmt.Errorf("failed to order modules: %v", err)
+	}
+
+	statuses := make([]ModuleStatus, 0, len(modules))
+	for _, module := range modules {
+		status, err := statusForModule(db, module)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// StatusForModule reports the migration state of a single module
+func StatusForModule(db *sql.DB, moduleName string) (ModuleStatus, error) {
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return ModuleStatus{}, err
+	}
+	return statusForModule(db, module)
+}
+
+// statusForModule compares the module's version table with its migration files
+func statusForModule(db *sql.DB, module app.Module) (ModuleStatus, error) {
+	status := ModuleStatus{Module: module.Name(), Pending: []string{}, Contract: []string{}}
+
+	version, dirty, err := readVersion(db, VersionTable(module.Name()))
+	if err != nil {
+		return status, fmt.Errorf("failed to read version for module %s: %v", module.Name(), err)
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	files, err := moduleMigrations(module, source.Up)
+	if err != nil {
+		return status, fmt.Errorf("failed to list migration files for module %s: %v", module.Name(), err)
+	}
+	for _, file := range files {
+		if version == nil || file.Version > *version {
+			status.Pending = append(status.Pending, file.Raw)
+		}
+	}
+
+	contract, err := pendingContracts(module, files, version)
+	if err != nil {
+		return status, err
+	}
+	status.Contract = append(status.Contract, contract...)
+
+	return status, nil
+}
+
+// readVersion reads a module's version table without creating it, returning nil if nothing was applied
+func readVersion(db *sql.DB, table string) (*uint, bool, error) {
+	var exists int
+	query := "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	if err := db.QueryRow(query, table).Scan(&exists); err != nil {
+		return nil, false, err
+	}
+	if exists == 0 {
+		return nil, false, nil
+	}
+
+	var version int64
+	var dirty bool
+	err := db.QueryRow("SELECT version, dirty FROM `"+table+"` LIMIT 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	// A failed first migration is recorded as a dirty nil version (-1)
+	if version < 0 {
+		return nil, dirty, nil
+	}
+
+	v := uint(version)
+	return &v, dirty, nil
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStatusForModule(t *testing.T) {
+	module := testModule{name: "status_test", files: fstest.MapFS{
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"2_add_full_name.up.sql":  {Data: []byte("ALTER TABLE users ADD COLUMN full_name VARCHAR(255);")},
+		"3_drop_name.up.sql":      {Data: []byte("-- phase: contract\nALTER TABLE users DROP COLUMN name;")},
+	}}
+
+	tests := []struct {
+		name     string
+		version  int64 // 0 leaves the version table out
+		dirty    bool
+		want     string
+		pending  string
+		contract string
+	}{
+		{"nothing applied", 0, false, "none", "1_create_users.up.sql 2_add_full_name.up.sql 3_drop_name.up.sql", "3_drop_name.up.sql"},
+		{"contract held", 2, false, "2", "3_drop_name.up.sql", "3_drop_name.up.sql"},
+		{"failed migration", 2, true, "2", "3_drop_name.up.sql", "3_drop_name.up.sql"},
+		{"up to date", 3, false, "3", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := openMemDB(t)
+			if tt.version > 0 {
+				setVersion(t, db, module.name, tt.version)
+				if _, err := db.Exec("UPDATE `"+VersionTable(module.name)+"` SET dirty = ?", tt.dirty); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			status, err := statusForModule(db, module)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if formatVersion(status.Version) != tt.want || status.Dirty != tt.dirty {
+				t.Errorf("Expected version %s (dirty %t), got %s (dirty %t)", tt.want, tt.dirty, formatVersion(status.Version), status.Dirty)
+			}
+			if got := strings.Join(status.Pending, " "); got != tt.pending {
+				t.Errorf("Expected pending %q, got %q", tt.pending, got)
+			}
+			if got := strings.Join(status.Contract, " "); got != tt.contract {
+				t.Errorf("Expected contract %q, got %q", tt.contract, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TenantConnector opens a connection pool to a tenant's schema
+type TenantConnector func(schema string) (*sql.DB, error)
+
+var (
+	// connectTenant opens tenant schemas for multi-tenant runs; set with UseTenants
+	connectTenant TenantConnector
+	// tenantConcurrency bounds how many tenant schemas are migrated at once
+	tenantConcurrency = 4
+)
+
+// tenantSchema matches the schema names accepted as tenants
+var tenantSchema = regexp.MustCompile(`^[A-Za-z0-9_$]+$`)
+
+// UseTenants sets how tenant schemas are opened and how many are migrated at once
+func UseTenants(connect TenantConnector, concurrency int) {
+	connectTenant = connect
+	tenantConcurrency = max(concurrency, 1)
+}
+
+// TenantResult is the outcome of running a command against one tenant schema
+type TenantResult struct {
+	Tenant     string `json:"tenant"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// TenantFailures is returned when a multi-tenant run fails for some tenants; the others completed
+type TenantFailures struct {
+	Total  int
+	Failed []TenantResult
+}
+
+// Error summarises the failed tenants so the run can be retried for just those
+func (f *TenantFailures) Error() string {
+	names := make([]string, 0, len(f.Failed))
+	details := make([]string, 0, len(f.Failed))
+	for _, result := range f.Failed {
+		names = append(names, result.Tenant)
+		details = append(details, fmt.Sprintf("  %s: %s", result.Tenant, result.Error))
+	}
+	return fmt.Sprintf("%d of %d tenant(s) failed:\n%s\nretry them with --tenants %s",
+		len(f.Failed), f.Total, strings.Join(details, "\n"), strings.Join(names, ","))
+}
+
+// TenantStatus is the migration state of every module in one tenant schema
+type TenantStatus struct {
+	Tenant  string         `json:"tenant"`
+	Modules []ModuleStatus `json:"modules"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// LoadTenants returns the tenant schemas listed in table, which must have a schema_name column
+func LoadTenants(db *sql.DB, table string) ([]string, error) {
+	if !tenantSchema.MatchString(table) {
+		return nil, fmt.Errorf("invalid tenants table: %s", table)
+	}
+	rows, err := db.Query("SELECT schema_name FROM `" + table + "` ORDER BY schema_name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants from %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read tenants from %s: %v", table, err)
+		}
+		tenants = append(tenants, name)
+	}
+	return tenants, rows.Err()
+}
+
+// StatusForTenants reports the migration state of a specific module, or of every module when
+// moduleName is empty, in each tenant schema. Tenants that can't be read carry their error.
+func StatusForTenants(tenants []string, moduleName string) ([]TenantStatus, error) {
+	statuses := make([]TenantStatus, len(tenants))
+	index := make(map[string]int, len(tenants))
+	for i, tenant := range tenants {
+		statuses[i].Tenant = tenant
+		index[tenant] = i
+	}
+
+	results, err := forEachTenant(tenants, func(db *sql.DB, tenant string) error {
+		var modules []ModuleStatus
+		if moduleName != "" {
+			status, err := StatusForModule(db, moduleName)
+			if err != nil {
+				return err
+			}
+			modules = []ModuleStatus{status}
+		} else {
+			var err error
+			if modules, err = Status(db); err != nil {
+				return err
+			}
+		}
+		// Each tenant writes only its own entry
+		statuses[index[tenant]].Modules = modules
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, result := range results {
+		statuses[i].Error = result.Error
+	}
+	return statuses, nil
+}
+
+// runTenants runs fn against every tenant schema, at most tenantConcurrency at a time. Every
+// tenant is attempted; the error lists the ones that failed.
+func runTenants(tenants []string, fn func(db *sql.DB) error) error {
+	results, err := forEachTenant(tenants, func(db *sql.DB, tenant string) error {
+		return fn(db)
+	})
+	if err != nil {
+		return err
+	}
+
+	failures := &TenantFailures{Total: len(results)}
+	for _, result := range results {
+		if result.Error != "" {
+			failures.Failed = append(failures.Failed, result)
+		}
+	}
+	if len(failures.Failed) > 0 {
+		return failures
+	}
+	log.Printf("All %d tenant(s) migrated successfully!", len(results))
+	return nil
+}
+
+// forEachTenant opens each tenant schema and runs fn against it with bounded concurrency,
+// returning one result per tenant in the order given
+func forEachTenant(tenants []string, fn func(db *sql.DB, tenant string) error) ([]TenantResult, error) {
+	if connectTenant == nil {
+		return nil, fmt.Errorf("no tenant connector configured; call UseTenants first")
+	}
+	seen := make(map[string]bool, len(tenants))
+	for _, tenant := range tenants {
+		if !tenantSchema.MatchString(tenant) {
+			return nil, fmt.Errorf("invalid tenant schema name: %q", tenant)
+		}
+		if seen[tenant] {
+			return nil, fmt.Errorf("tenant %s listed twice", tenant)
+		}
+		seen[tenant] = true
+	}
+
+	results := make([]TenantResult, len(tenants))
+	slots := make(chan struct{}, tenantConcurrency)
+	var wg sync.WaitGroup
+	for i, tenant := range tenants {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			started := time.Now()
+			err := runTenant(tenant, fn)
+			results[i] = TenantResult{Tenant: tenant, DurationMs: time.Since(started).Milliseconds()}
+			if err != nil {
+				results[i].Error = err.Error()
+				log.Printf("Tenant %s failed after %s: %v", tenant, time.Since(started).Round(time.Millisecond), err)
+				return
+			}
+			log.Printf("Tenant %s done in %s", tenant, time.Since(started).Round(time.Millisecond))
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// runTenant opens a tenant schema, runs fn against it and closes it again
+func runTenant(tenant string, fn func(db *sql.DB, tenant string) error) error {
+	db, err := connectTenant(tenant)
+	if err != nil {
+		return fmt.Errorf("failed to connect to tenant schema: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		return fmt.Errorf("failed to connect to tenant schema: %v", err)
+	}
+	return fn(db, tenant)
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRunTenants_BoundsConcurrencyAndSummarisesFailures(t *testing.T) {
+	var mu sync.Mutex
+	schemas := make(map[*sql.DB]string)
+	UseTenants(func(schema string) (*sql.DB, error) {
+		db, err := sql.Open("migrations_memdb", schema)
+		mu.Lock()
+		schemas[db] = schema
+		mu.Unlock()
+		return db, err
+	}, 2)
+	defer UseTenants(nil, 4)
+
+	running, peak := 0, 0
+	err := runTenants([]string{"acme", "globex", "initech", "umbrella", "hooli"}, func(db *sql.DB) error {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		tenant := schemas[db]
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+
+		if tenant == "globex" || tenant == "hooli" {
+			return errors.New("table already exists")
+		}
+		return nil
+	})
+
+	var failures *TenantFailures
+	if !errors.As(err, &failures) {
+		t.Fatalf("Expected tenant failures, got %v", err)
+	}
+	if failures.Total != 5 || len(failures.Failed) != 2 || failures.Failed[0].Tenant != "globex" || failures.Failed[1].Tenant != "hooli" {
+		t.Errorf("Expected globex and hooli to fail out of 5, got %+v", failures)
+	}
+	if !strings.Contains(err.Error(), "--tenants globex,hooli") {
+		t.Errorf("Expected a retry hint in %q", err.Error())
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 tenants at once, got %d", peak)
+	}
+}
@@ -0,0 +1,155 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dialect describes the SQL a generator writes for one database
+type Dialect struct {
+	Name          string
+	UUIDColumn    string // id column holding a generated UUID primary key
+	TimestampType string // column type of created_at and updated_at
+	OnUpdate      string // clause appended to updated_at when the database refreshes it itself
+	// UpdateTrigger keeps updated_at current where the database has no ON UPDATE clause, and
+	// DropUpdateFunction removes what dropping the table leaves behind; %[1]s is the table name
+	UpdateTrigger      string
+	DropUpdateFunction string
+	// DropIndexSQL and DropForeignKeySQL drop an index or constraint; %[1]s is its name and %[2]s the table.
+	// An empty DropForeignKeySQL means foreign keys can't be changed on an existing table.
+	DropIndexSQL      string
+	DropForeignKeySQL string
+}
+
+// Dialects lists the databases the generators can write SQL for
+var Dialects = map[string]Dialect{
+	"mysql": {
+		Name:              "mysql",
+		UUIDColumn:        "id CHAR(36) PRIMARY KEY DEFAULT (UUID())",
+		TimestampType:     "TIMESTAMP",
+		OnUpdate:          " ON UPDATE CURRENT_TIMESTAMP",
+		DropIndexSQL:      "DROP INDEX %[1]s ON %[2]s;",
+		DropForeignKeySQL: "ALTER TABLE %[2]s DROP FOREIGN KEY %[1]s;",
+	},
+	"postgres": {
+		Name:          "postgres",
+		UUIDColumn:    "id UUID PRIMARY KEY DEFAULT gen_random_uuid()",
+		TimestampType: "TIMESTAMPTZ",
+		UpdateTrigger: `CREATE OR REPLACE FUNCTION %[1]s_set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER %[1]s_set_updated_at BEFORE UPDATE ON %[1]s
+FOR EACH ROW EXECUTE FUNCTION %[1]s_set_updated_at();`,
+		DropUpdateFunction: `DROP FUNCTION IF EXISTS %[1]s_set_updated_at();`,
+		DropIndexSQL:       "DROP INDEX IF EXISTS %[1]s;",
+		DropForeignKeySQL:  "ALTER TABLE %[2]s DROP CONSTRAINT %[1]s;",
+	},
+	"sqlite": {
+		Name: "sqlite",
+		// SQLite has no UUID function, so build a version 4 UUID from random bytes
+		UUIDColumn: "id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || " +
+			"substr(hex(randomblob(2)), 2) || '-' || substr('89ab', abs(random()) % 4 + 1, 1) || " +
+			"substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))))",
+		TimestampType: "TIMESTAMP",
+		UpdateTrigger: `CREATE TRIGGER %[1]s_set_updated_at AFTER UPDATE ON %[1]s
+FOR EACH ROW WHEN NEW.updated_at = OLD.updated_at
+BEGIN
+    UPDATE %[1]s SET updated_at = CURRENT_TIMESTAMP WHERE id = OLD.id;
+END;`,
+		DropIndexSQL: "DROP INDEX IF EXISTS %[1]s;",
+	},
+}
+
+// LookupDialect returns the dialect with the given name, accepting common aliases
+func LookupDialect(name string) (Dialect, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "mysql", "mariadb":
+		return Dialects["mysql"], nil
+	case "postgres", "postgresql", "pgx":
+		return Dialects["postgres"], nil
+	case "sqlite", "sqlite3":
+		return Dialects["sqlite"], nil
+	}
+
+	names := make([]string, 0, len(Dialects))
+	for name := range Dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return Dialect{}, fmt.Errorf("unsupported database dialect %q (use %s)", name, strings.Join(names, ", "))
+}
+
+// CreateTable renders a table with a UUID primary key, the given column and constraint definitions
+// and created_at/updated_at columns, plus whatever the dialect needs to keep updated_at current
+func (d Dialect) CreateTable(table string, definitions ...string) string {
+	lines := append([]string{d.UUIDColumn}, definitions...)
+	lines = append(lines,
+		fmt.Sprintf("created_at %s NOT NULL DEFAULT CURRENT_TIMESTAMP", d.TimestampType),
+		fmt.Sprintf("updated_at %s NOT NULL DEFAULT CURRENT_TIMESTAMP%s", d.TimestampType, d.OnUpdate),
+	)
+	// Constraints must follow the columns
+	sort.SliceStable(lines, func(i, j int) bool {
+		return !strings.HasPrefix(lines[i], "CONSTRAINT ") && strings.HasPrefix(lines[j], "CONSTRAINT ")
+	})
+	sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n    %s\n);", table, strings.Join(lines, ",\n    "))
+	if d.UpdateTrigger != "" {
+		sql += "\n\n" + fmt.Sprintf(d.UpdateTrigger, table)
+	}
+	return sql
+}
+
+// DropTable renders the statements undoing CreateTable; dropping the table also drops its triggers
+func (d Dialect) DropTable(table string) string {
+	sql := fmt.Sprintf("DROP TABLE IF EXISTS %s;", table)
+	if d.DropUpdateFunction != "" {
+		sql += "\n" + fmt.Sprintf(d.DropUpdateFunction, table)
+	}
+	return sql
+}
+
+// AddColumn renders adding a column to an existing table
+func (d Dialect) AddColumn(table, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, column)
+}
+
+// DropColumn renders dropping a column
+func (d Dialect) DropColumn(table, name string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, name)
+}
+
+// CreateIndex renders creating an index
+func (d Dialect) CreateIndex(name, table string, columns []string, unique bool) string {
+	keyword := "INDEX"
+	if unique {
+		keyword = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("CREATE %s %s ON %s (%s);", keyword, name, table, strings.Join(columns, ", "))
+}
+
+// DropIndex renders dropping an index
+func (d Dialect) DropIndex(name, table string) string {
+	return fmt.Sprintf(d.DropIndexSQL, name, table)
+}
+
+// ForeignKey renders a foreign key constraint definition for CREATE or ALTER TABLE
+func (d Dialect) ForeignKey(key ForeignKey) string {
+	return "CONSTRAINT " + key.Name + " " + foreignKeyClause(key)
+}
+
+// AddForeignKey renders adding a foreign key to an existing table
+func (d Dialect) AddForeignKey(table string, key ForeignKey) (string, error) {
+	if d.DropForeignKeySQL == "" {
+		return "", fmt.Errorf("%s cannot add foreign keys to an existing table; declare them when creating it", d.Name)
+	}
+	return fmt.Sprintf("ALTER TABLE %s ADD %s;", table, d.ForeignKey(key)), nil
+}
+
+// DropForeignKey renders dropping a foreign key
+func (d Dialect) DropForeignKey(name, table string) string {
+	return fmt.Sprintf(d.DropForeignKeySQL, name, table)
+}
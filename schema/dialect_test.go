@@ -0,0 +1,30 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDialect_CreateTableKeepsUpdatedAtCurrent(t *testing.T) {
+	cases := map[string][]string{
+		"mysql":      {"id CHAR(36) PRIMARY KEY DEFAULT (UUID())", "DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"},
+		"postgresql": {"id UUID PRIMARY KEY DEFAULT gen_random_uuid()", "CREATE TRIGGER orders_set_updated_at BEFORE UPDATE ON orders"},
+		"sqlite3":    {"id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4))", "CREATE TRIGGER orders_set_updated_at AFTER UPDATE ON orders"},
+	}
+	for name, expected := range cases {
+		dialect, err := LookupDialect(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sql := dialect.CreateTable("orders")
+		for _, want := range expected {
+			if !strings.Contains(sql, want) {
+				t.Errorf("Expected the %s table to contain %q, got:\n%s", name, want, sql)
+			}
+		}
+	}
+
+	if _, err := LookupDialect("oracle"); err == nil {
+		t.Error("Expected an error for an unsupported dialect")
+	}
+}
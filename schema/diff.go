@@ -0,0 +1,293 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Changes holds the statements that move a database from one schema to another and back.
+// Up statements run in order; Down statements undo them and are already in reverse order.
+type Changes struct {
+	Up   []string
+	Down []string
+}
+
+// Empty reports whether the schemas are equivalent
+func (c Changes) Empty() bool {
+	for _, statement := range c.Up {
+		if !strings.HasPrefix(statement, "--") {
+			return false
+		}
+	}
+	return true
+}
+
+// Diff compares the current tables with the desired ones and returns the MySQL statements
+// that turn current into desired. Columns, indexes and foreign keys that only exist in the
+// database are reported as comments rather than dropped, since they may belong to other code.
+func Diff(current, desired []Table) Changes {
+	existing := make(map[string]Table, len(current))
+	for _, table := range current {
+		existing[table.Name] = table
+	}
+
+	var changes Changes
+	var down []string
+	for _, table := range orderByForeignKeys(desired) {
+		have, ok := existing[table.Name]
+		if !ok {
+			changes.Up = append(changes.Up, CreateTable(table))
+			down = append(down, fmt.Sprintf("DROP TABLE IF EXISTS %s;", table.Name))
+			continue
+		}
+		up, undo := diffTable(have, table)
+		changes.Up = append(changes.Up, up...)
+		down = append(down, undo...)
+	}
+
+	for i := len(down) - 1; i >= 0; i-- {
+		changes.Down = append(changes.Down, down[i])
+	}
+	return changes
+}
+
+// CreateTable renders the CREATE TABLE statement for a table
+func CreateTable(table Table) string {
+	var lines []string
+	primaryKey := table.PrimaryKey()
+	for _, column := range table.Columns {
+		definition := columnDefinition(column)
+		if column.PrimaryKey && len(primaryKey) == 1 {
+			definition += " PRIMARY KEY"
+		}
+		lines = append(lines, definition)
+	}
+	if len(primaryKey) > 1 {
+		lines = append(lines, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(primaryKey, ", ")))
+	}
+	for _, index := range table.Indexes {
+		keyword := "INDEX"
+		if index.Unique {
+			keyword = "UNIQUE INDEX"
+		}
+		lines = append(lines, fmt.Sprintf("%s %s (%s)", keyword, index.Name, strings.Join(index.Columns, ", ")))
+	}
+	for _, key := range table.ForeignKeys {
+		lines = append(lines, "CONSTRAINT "+key.Name+" "+foreignKeyClause(key))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n    %s\n);", table.Name, strings.Join(lines, ",\n    "))
+}
+
+// diffTable returns the statements that change one existing table, and their inverses in apply order
+func diffTable(have, want Table) (up, down []string) {
+	for _, column := range want.Columns {
+		current, ok := have.Column(column.Name)
+		switch {
+		case !ok:
+			up = append(up, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", want.Name, columnDefinition(column)))
+			down = append(down, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", want.Name, column.Name))
+		case !sameColumn(current, column):
+			up = append(up, fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", want.Name, columnDefinition(column)))
+			down = append(down, fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", want.Name, columnDefinition(current)))
+		}
+	}
+	for _, column := range have.Columns {
+		if _, ok := want.Column(column.Name); !ok {
+			up = append(up, fmt.Sprintf("-- %s.%s exists in the database but not in the models", have.Name, column.Name))
+		}
+	}
+
+	for _, index := range want.Indexes {
+		if findIndex(have.Indexes, index) >= 0 {
+			continue
+		}
+		keyword := "INDEX"
+		if index.Unique {
+			keyword = "UNIQUE INDEX"
+		}
+		up = append(up, fmt.Sprintf("CREATE %s %s ON %s (%s);", keyword, index.Name, want.Name, strings.Join(index.Columns, ", ")))
+		down = append(down, fmt.Sprintf("DROP INDEX %s ON %s;", index.Name, want.Name))
+	}
+	for _, index := range have.Indexes {
+		if findIndex(want.Indexes, index) < 0 && !backsForeignKey(have, index) {
+			up = append(up, fmt.Sprintf("-- index %s on %s exists in the database but not in the models", index.Name, have.Name))
+		}
+	}
+
+	for _, key := range want.ForeignKeys {
+		current, ok := findForeignKey(have.ForeignKeys, key.Column)
+		if ok && sameForeignKey(current, key) {
+			continue
+		}
+		if ok {
+			up = append(up, fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s;", want.Name, current.Name))
+			down = append(down, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;", want.Name, current.Name, foreignKeyClause(current)))
+		}
+		up = append(up, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;", want.Name, key.Name, foreignKeyClause(key)))
+		down = append(down, fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s;", want.Name, key.Name))
+	}
+	for _, key := range have.ForeignKeys {
+		if _, ok := findForeignKey(want.ForeignKeys, key.Column); !ok {
+			up = append(up, fmt.Sprintf("-- foreign key %s on %s exists in the database but not in the models", key.Name, have.Name))
+		}
+	}
+	return up, down
+}
+
+// columnDefinition renders a column as it appears in CREATE TABLE or MODIFY COLUMN, without PRIMARY KEY
+func columnDefinition(column Column) string {
+	parts := []string{column.Name, column.Type}
+	if !column.Nullable {
+		parts = append(parts, "NOT NULL")
+	}
+	if column.AutoIncrement {
+		parts = append(parts, "AUTO_INCREMENT")
+	}
+	if column.Default != nil {
+		parts = append(parts, "DEFAULT "+*column.Default)
+	}
+	if column.OnUpdate != "" {
+		parts = append(parts, "ON UPDATE "+column.OnUpdate)
+	}
+	return strings.Join(parts, " ")
+}
+
+// foreignKeyClause renders FOREIGN KEY ... REFERENCES ... with its rules
+func foreignKeyClause(key ForeignKey) string {
+	clause := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)", key.Column, key.RefTable, key.RefColumn)
+	if rule := normalizeRule(key.OnDelete); rule != "" {
+		clause += " ON DELETE " + rule
+	}
+	if rule := normalizeRule(key.OnUpdate); rule != "" {
+		clause += " ON UPDATE " + rule
+	}
+	return clause
+}
+
+// sameColumn compares two columns ignoring spelling differences MySQL doesn't preserve
+func sameColumn(a, b Column) bool {
+	return normalizeType(a.Type) == normalizeType(b.Type) &&
+		a.Nullable == b.Nullable &&
+		a.AutoIncrement == b.AutoIncrement &&
+		normalizeDefault(a.Default) == normalizeDefault(b.Default) &&
+		strings.EqualFold(a.OnUpdate, b.OnUpdate)
+}
+
+var (
+	displayWidth = regexp.MustCompile(`^(SMALLINT|MEDIUMINT|INT|BIGINT)\(\d+\)`)
+	number       = regexp.MustCompile(`^-?[0-9.]+$`)
+)
+
+// normalizeType maps type aliases and display widths to one spelling
+func normalizeType(t string) string {
+	t = strings.ToUpper(strings.TrimSpace(t))
+	switch t {
+	case "BOOLEAN", "BOOL":
+		return "TINYINT(1)"
+	case "INTEGER":
+		return "INT"
+	}
+	if strings.HasPrefix(t, "TINYINT(") && t != "TINYINT(1)" {
+		return "TINYINT" + t[strings.Index(t, ")")+1:]
+	}
+	return displayWidth.ReplaceAllString(t, "$1")
+}
+
+// normalizeDefault maps a default expression to a comparable form
+func normalizeDefault(def *string) string {
+	if def == nil {
+		return ""
+	}
+	value := strings.ToUpper(strings.TrimSpace(*def))
+	for strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		value = value[1 : len(value)-1]
+	}
+	switch value {
+	case "TRUE":
+		return "1"
+	case "FALSE":
+		return "0"
+	case "NOW()", "CURRENT_TIMESTAMP()":
+		return "CURRENT_TIMESTAMP"
+	}
+	// Numbers may be stored quoted or bare
+	if unquoted := strings.Trim(value, "'"); number.MatchString(unquoted) {
+		return unquoted
+	}
+	return value
+}
+
+// normalizeRule treats MySQL's implicit rules as no rule
+func normalizeRule(rule string) string {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	if rule == "RESTRICT" || rule == "NO ACTION" {
+		return ""
+	}
+	return rule
+}
+
+// findIndex returns the position of an index with the same columns and uniqueness, or -1
+func findIndex(indexes []Index, index Index) int {
+	for i, candidate := range indexes {
+		if candidate.Unique == index.Unique && strings.Join(candidate.Columns, ",") == strings.Join(index.Columns, ",") {
+			return i
+		}
+	}
+	return -1
+}
+
+// backsForeignKey reports whether MySQL created an index only to support a foreign key
+func backsForeignKey(table Table, index Index) bool {
+	for _, key := range table.ForeignKeys {
+		if key.Name == index.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// findForeignKey returns the foreign key declared on a column
+func findForeignKey(keys []ForeignKey, column string) (ForeignKey, bool) {
+	for _, key := range keys {
+		if key.Column == column {
+			return key, true
+		}
+	}
+	return ForeignKey{}, false
+}
+
+// sameForeignKey compares two foreign keys ignoring their names
+func sameForeignKey(a, b ForeignKey) bool {
+	return a.RefTable == b.RefTable && a.RefColumn == b.RefColumn &&
+		normalizeRule(a.OnDelete) == normalizeRule(b.OnDelete) &&
+		normalizeRule(a.OnUpdate) == normalizeRule(b.OnUpdate)
+}
+
+// orderByForeignKeys orders tables so that referenced tables come before the tables referencing them
+func orderByForeignKeys(tables []Table) []Table {
+	byName := make(map[string]Table, len(tables))
+	for _, table := range tables {
+		byName[table.Name] = table
+	}
+
+	var ordered []Table
+	visited := make(map[string]bool)
+	var visit func(table Table)
+	visit = func(table Table) {
+		if visited[table.Name] {
+			return
+		}
+		visited[table.Name] = true
+		for _, key := range table.ForeignKeys {
+			if ref, ok := byName[key.RefTable]; ok {
+				visit(ref)
+			}
+		}
+		ordered = append(ordered, table)
+	}
+	for _, table := range tables {
+		visit(table)
+	}
+	return ordered
+}
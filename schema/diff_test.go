@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"auto_verse/Modules/users/models"
+	"strings"
+	"testing"
+)
+
+func expr(value string) *string {
+	return &value
+}
+
+// usersSchema is what information_schema reports after the users migrations have run
+func usersSchema() []Table {
+	timestamps := []Column{
+		{Name: "created_at", Type: "TIMESTAMP", Default: expr("CURRENT_TIMESTAMP")},
+		{Name: "updated_at", Type: "TIMESTAMP", Default: expr("CURRENT_TIMESTAMP"), OnUpdate: "CURRENT_TIMESTAMP"},
+	}
+	users := Table{
+		Name: "users",
+		Columns: append([]Column{
+			{Name: "id", Type: "CHAR(36)", Default: expr("(uuid())"), PrimaryKey: true},
+			{Name: "email", Type: "VARCHAR(255)"},
+			{Name: "phone", Type: "VARCHAR(15)", Nullable: true},
+			{Name: "username", Type: "VARCHAR(50)"},
+			{Name: "password", Type: "VARCHAR(255)"},
+			{Name: "auth_type", Type: "VARCHAR(50)", Default: expr("'email'")},
+			{Name: "is_verified", Type: "TINYINT(1)", Default: expr("0")},
+		}, append(timestamps, Column{Name: "deleted_at", Type: "TIMESTAMP", Nullable: true})...),
+		Indexes: []Index{
+			{Name: "email", Columns: []string{"email"}, Unique: true},
+			{Name: "phone", Columns: []string{"phone"}, Unique: true},
+			{Name: "username", Columns: []string{"username"}, Unique: true},
+		},
+	}
+	details := Table{
+		Name: "users_details",
+		Columns: append([]Column{
+			{Name: "id", Type: "CHAR(36)", Default: expr("(uuid())"), PrimaryKey: true},
+			{Name: "user_id", Type: "CHAR(36)"},
+			{Name: "first_name", Type: "VARCHAR(50)"},
+			{Name: "last_name", Type: "VARCHAR(50)"},
+			{Name: "profile_pic", Type: "TEXT", Nullable: true},
+			{Name: "gender", Type: "VARCHAR(10)", Nullable: true},
+			{Name: "date_of_birth", Type: "TIMESTAMP", Nullable: true},
+			{Name: "about_me", Type: "TEXT", Nullable: true},
+		}, timestamps...),
+		Indexes:     []Index{{Name: "user_id", Columns: []string{"user_id"}, Unique: true}},
+		ForeignKeys: []ForeignKey{{Name: "users_details_ibfk_1", Column: "user_id", RefTable: "users", RefColumn: "id", OnDelete: "CASCADE", OnUpdate: "NO ACTION"}},
+	}
+	return []Table{users, details}
+}
+
+func TestDiff_UsersModelsAgainstMigratedSchema(t *testing.T) {
+	desired, err := FromModels([]any{models.Users{}, models.UserDetails{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Diff(usersSchema(), desired)
+
+	// The models index deleted_at, which the hand-written migration never did
+	expectedUp := []string{"CREATE INDEX idx_users_deleted_at ON users (deleted_at);"}
+	if strings.Join(changes.Up, "\n") != strings.Join(expectedUp, "\n") {
+		t.Errorf("Expected up %q, got %q", expectedUp, changes.Up)
+	}
+	expectedDown := []string{"DROP INDEX idx_users_deleted_at ON users;"}
+	if strings.Join(changes.Down, "\n") != strings.Join(expectedDown, "\n") {
+		t.Errorf("Expected down %q, got %q", expectedDown, changes.Down)
+	}
+}
+
+func TestDiff_CreatesMissingTablesInForeignKeyOrder(t *testing.T) {
+	desired, err := FromModels([]any{models.UserDetails{}, models.Users{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Diff(nil, desired)
+
+	if len(changes.Up) != 2 || !strings.HasPrefix(changes.Up[0], "CREATE TABLE users (") || !strings.HasPrefix(changes.Up[1], "CREATE TABLE users_details (") {
+		t.Fatalf("Expected users to be created before users_details, got %q", changes.Up)
+	}
+	if !strings.Contains(changes.Up[1], "FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE") {
+		t.Errorf("Expected the users_details foreign key, got %s", changes.Up[1])
+	}
+	expectedDown := []string{"DROP TABLE IF EXISTS users_details;", "DROP TABLE IF EXISTS users;"}
+	if strings.Join(changes.Down, "\n") != strings.Join(expectedDown, "\n") {
+		t.Errorf("Expected down %q, got %q", expectedDown, changes.Down)
+	}
+}
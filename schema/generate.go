@@ -0,0 +1,372 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var varcharType = regexp.MustCompile(`^VARCHAR\((\d+)\)$`)
+
+// relation is an association field added to a generated struct
+type relation struct {
+	field  string
+	goType string
+	json   string
+	tag    string
+}
+
+// GenerateModels renders a Go model struct for every table, keyed by table name.
+// Nullable columns become pointers, columns carry json and gorm tags that FromModels
+// reads back, and foreign keys between the given tables become association fields.
+func GenerateModels(tables []Table, packageName string) (map[string][]byte, error) {
+	structs := make(map[string]string, len(tables))
+	for _, table := range tables {
+		structs[table.Name] = StructName(table.Name)
+	}
+
+	relations := make(map[string][]relation)
+	for _, table := range tables {
+		// Tables referenced by more than one key get an inverse relation per key, named after it
+		references := make(map[string]int)
+		for _, key := range table.ForeignKeys {
+			references[key.RefTable]++
+		}
+
+		for _, key := range table.ForeignKeys {
+			target, ok := structs[key.RefTable]
+			if !ok {
+				continue
+			}
+			keyField := FieldName(key.Column)
+			tag := "foreignKey:" + keyField
+			if key.RefColumn != "id" {
+				tag += ";references:" + FieldName(key.RefColumn)
+			}
+			if rule := normalizeRule(key.OnDelete); rule != "" {
+				tag += ";constraint:OnDelete:" + rule
+			}
+
+			// Belongs-to on the table holding the key
+			name := FieldName(strings.TrimSuffix(key.Column, "_id"))
+			if name == keyField {
+				name = target
+			}
+			relations[table.Name] = append(relations[table.Name], relation{
+				field:  name,
+				goType: "*" + target,
+				json:   SnakeCase(name),
+				tag:    tag,
+			})
+
+			// Has-one or has-many on the referenced table
+			inverse := relation{field: structs[table.Name], goType: "*" + structs[table.Name], json: table.Name, tag: "foreignKey:" + keyField}
+			if references[key.RefTable] > 1 {
+				inverse.field = FieldName(strings.TrimSuffix(key.Column, "_id")) + structs[table.Name]
+				inverse.json = SnakeCase(inverse.field)
+			}
+			if !uniqueColumn(table, key.Column) {
+				inverse.goType = "[]" + structs[table.Name]
+			}
+			relations[key.RefTable] = append(relations[key.RefTable], inverse)
+		}
+	}
+
+	files := make(map[string][]byte, len(tables))
+	for _, table := range tables {
+		source, err := generateModel(table, packageName, relations[table.Name])
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate model for %s: %v", table.Name, err)
+		}
+		files[table.Name] = source
+	}
+	return files, nil
+}
+
+// WriteModels writes one <table>.go file per table into dir.
+// Existing files are left alone unless overwrite is set, and nothing is written when a file
+// exists or a struct would redeclare a name the package already declares in another file.
+func WriteModels(dir string, tables []Table, overwrite bool) ([]string, error) {
+	packageName := filepath.Base(dir)
+	files, err := GenerateModels(tables, packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(tables))
+	replaced := make(map[string]bool)
+	for _, table := range tables {
+		path := filepath.Join(dir, table.Name+".go")
+		if _, err := os.Stat(path); err == nil {
+			if !overwrite {
+				return nil, fmt.Errorf("%s already exists", path)
+			}
+			replaced[path] = true
+		}
+		paths = append(paths, path)
+	}
+
+	declared, err := declaredNames(dir, packageName, replaced)
+	if err != nil {
+		return nil, err
+	}
+	generated := make(map[string]string, len(tables))
+	for _, table := range tables {
+		name := StructName(table.Name)
+		if other, ok := generated[name]; ok {
+			return nil, fmt.Errorf("tables %s and %s would both generate type %s", other, table.Name, name)
+		}
+		generated[name] = table.Name
+		if path, ok := declared[name]; ok {
+			return nil, fmt.Errorf("the model of table %s would redeclare %s, already declared in %s", table.Name, name, path)
+		}
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create models directory: %v", err)
+	}
+	for i, table := range tables {
+		if err := os.WriteFile(paths[i], files[table.Name], 0644); err != nil {
+			return paths[:i], fmt.Errorf("failed to write %s: %v", paths[i], err)
+		}
+	}
+	return paths, nil
+}
+
+// declaredNames returns the package-level names declared by the Go files of a package, mapped to
+// the file declaring them. Files in skip, which are about to be replaced, are left out.
+func declaredNames(dir, packageName string, skip map[string]bool) (map[string]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		if skip[path] {
+			continue
+		}
+		file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		if file.Name.Name != packageName {
+			continue
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					names[decl.Name.Name] = path
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						names[spec.Name.Name] = path
+					case *ast.ValueSpec:
+						for _, name := range spec.Names {
+							names[name.Name] = path
+						}
+					}
+				}
+			}
+		}
+	}
+	return names, nil
+}
+
+// StructName converts a table name such as users_details to UsersDetails
+func StructName(table string) string {
+	return FieldName(table)
+}
+
+// FieldName converts a column name such as user_id to UserID
+func FieldName(column string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(column, "_") {
+		if part == "" {
+			continue
+		}
+		switch upper := strings.ToUpper(part); upper {
+		case "ID", "URL", "UUID", "IP", "API", "JSON":
+			b.WriteString(upper)
+		default:
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// generateModel renders the file holding one table's struct
+func generateModel(table Table, packageName string, relations []relation) ([]byte, error) {
+	name := StructName(table.Name)
+	usesTime := false
+
+	var fields bytes.Buffer
+	used := make(map[string]bool)
+	for _, column := range table.Columns {
+		used[FieldName(column.Name)] = true
+		goType := goType(column)
+		if strings.HasSuffix(goType, "time.Time") {
+			usesTime = true
+		}
+		tag := fmt.Sprintf("json:%q", column.Name)
+		if gorm := gormTag(table, column, goType); gorm != "" {
+			tag += fmt.Sprintf(" gorm:%q", gorm)
+		}
+		fmt.Fprintf(&fields, "\t%s %s `%s`\n", FieldName(column.Name), goType, tag)
+	}
+	// A relation whose field name is already taken is left out rather than redeclaring the field
+	var associations []relation
+	for _, rel := range relations {
+		if !used[rel.field] {
+			used[rel.field] = true
+			associations = append(associations, rel)
+		}
+	}
+	if len(associations) > 0 {
+		fields.WriteString("\n\t// Associations\n")
+		for _, rel := range associations {
+			fmt.Fprintf(&fields, "\t%s %s `json:\"%s,omitempty\" gorm:\"%s\"`\n", rel.field, rel.goType, rel.json, rel.tag)
+		}
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Generated from the %s table by make:models; review before editing.\n\npackage %s\n\n", table.Name, packageName)
+	if usesTime {
+		src.WriteString("import \"time\"\n\n")
+	}
+	fmt.Fprintf(&src, "// %s represents a row of the %s table\ntype %s struct {\n%s}\n", name, table.Name, name, fields.String())
+	if SnakeCase(name)+pluralSuffix(name) != table.Name {
+		fmt.Fprintf(&src, "\n// TableName returns the table holding %s rows\nfunc (%s) TableName() string {\n\treturn %q\n}\n", name, name, table.Name)
+	}
+	return format.Source(src.Bytes())
+}
+
+// pluralSuffix mirrors the pluralisation TableName applies to struct names
+func pluralSuffix(name string) string {
+	if strings.HasSuffix(SnakeCase(name), "s") {
+		return ""
+	}
+	return "s"
+}
+
+// goType maps a MySQL column type to a Go field type; nullable columns become pointers
+func goType(column Column) string {
+	t := normalizeType(column.Type)
+	base := strings.TrimSpace(strings.TrimSuffix(t, " UNSIGNED"))
+	if i := strings.Index(base, "("); i >= 0 && base != "TINYINT(1)" {
+		base = base[:i]
+	}
+
+	var goType string
+	switch base {
+	case "TINYINT(1)", "BIT":
+		goType = "bool"
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT":
+		goType = "int"
+	case "BIGINT":
+		goType = "int64"
+	case "DECIMAL", "FLOAT", "DOUBLE":
+		goType = "float64"
+	case "DATE", "DATETIME", "TIMESTAMP":
+		goType = "time.Time"
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY":
+		return "[]byte"
+	default:
+		goType = "string"
+	}
+	if column.Nullable {
+		return "*" + goType
+	}
+	return goType
+}
+
+// gormTag renders the gorm tag describing a column
+func gormTag(table Table, column Column, goType string) string {
+	var parts []string
+	if column.PrimaryKey {
+		parts = append(parts, "primaryKey")
+	}
+	if column.AutoIncrement {
+		parts = append(parts, "autoIncrement")
+	}
+
+	if match := varcharType.FindStringSubmatch(column.Type); match != nil {
+		if match[1] != "255" {
+			parts = append(parts, "size:"+match[1])
+		}
+	} else if mapped, err := mysqlType(fieldType(goType), nil); err != nil || normalizeType(mapped) != normalizeType(column.Type) {
+		parts = append(parts, "type:"+strings.ToLower(column.Type))
+	}
+
+	for _, index := range table.Indexes {
+		if len(index.Columns) != 1 || index.Columns[0] != column.Name {
+			continue
+		}
+		if index.Unique {
+			parts = append(parts, "uniqueIndex")
+		} else {
+			parts = append(parts, "index")
+		}
+		break
+	}
+
+	autoTime := column.Default != nil && normalizeDefault(column.Default) == "CURRENT_TIMESTAMP" && !column.Nullable
+	switch {
+	case autoTime && column.OnUpdate != "":
+		parts = append(parts, "autoUpdateTime")
+	case autoTime && column.Name == "created_at":
+		parts = append(parts, "autoCreateTime")
+	default:
+		if !column.Nullable && !column.PrimaryKey {
+			parts = append(parts, "not null")
+		}
+		if column.Default != nil {
+			parts = append(parts, "default:"+*column.Default)
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// fieldType returns the reflect type of a generated Go type name, ignoring pointers
+func fieldType(goType string) reflect.Type {
+	switch strings.TrimPrefix(goType, "*") {
+	case "bool":
+		return reflect.TypeOf(false)
+	case "int":
+		return reflect.TypeOf(0)
+	case "int64":
+		return reflect.TypeOf(int64(0))
+	case "float64":
+		return reflect.TypeOf(float64(0))
+	case "time.Time":
+		return timeType
+	case "[]byte":
+		return reflect.TypeOf([]byte(nil))
+	}
+	return reflect.TypeOf("")
+}
+
+// uniqueColumn reports whether a column has a single-column unique index or is the primary key
+func uniqueColumn(table Table, column string) bool {
+	if keys := table.PrimaryKey(); len(keys) == 1 && keys[0] == column {
+		return true
+	}
+	for _, index := range table.Indexes {
+		if index.Unique && len(index.Columns) == 1 && index.Columns[0] == column {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateModels_UsersSchema(t *testing.T) {
+	files, err := GenerateModels(usersSchema(), "models")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"users": {
+			"type Users struct {",
+			"Phone      *string    `json:\"phone\" gorm:\"size:15;uniqueIndex\"`",
+			"UpdatedAt  time.Time  `json:\"updated_at\" gorm:\"autoUpdateTime\"`",
+			"DeletedAt  *time.Time `json:\"deleted_at\"`",
+			"UsersDetails *UsersDetails `json:\"users_details,omitempty\" gorm:\"foreignKey:UserID\"`",
+		},
+		"users_details": {
+			"type UsersDetails struct {",
+			"UserID      string     `json:\"user_id\" gorm:\"type:char(36);uniqueIndex;not null\"`",
+			"AboutMe     *string    `json:\"about_me\" gorm:\"type:text\"`",
+			"User *Users `json:\"user,omitempty\" gorm:\"foreignKey:UserID;constraint:OnDelete:CASCADE\"`",
+		},
+	}
+	for table, lines := range expected {
+		source := string(files[table])
+		for _, line := range lines {
+			if !strings.Contains(source, line) {
+				t.Errorf("Expected the %s model to contain %q, got:\n%s", table, line, source)
+			}
+		}
+		if strings.Contains(source, "TableName()") {
+			t.Errorf("Expected no TableName method for %s, whose name follows the convention", table)
+		}
+	}
+}
+
+func TestWriteModels_RefusesClashesBeforeWriting(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "models")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := "package models\n\n// Users is the hand-written users model\ntype Users struct{}\n"
+	if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := WriteModels(dir, usersSchema(), false)
+	if err == nil || !strings.Contains(err.Error(), "would redeclare Users, already declared in "+filepath.Join(dir, "models.go")) {
+		t.Errorf("Expected the users model to clash with models.go, got %v", err)
+	}
+
+	// An existing target file stops the run before any other file is written
+	if err := os.WriteFile(filepath.Join(dir, "models.go"), []byte("package models\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "users_details.go"), []byte("package models\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := WriteModels(dir, usersSchema(), false); err == nil || !strings.Contains(err.Error(), "users_details.go already exists") {
+		t.Errorf("Expected the existing users_details.go to be refused, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "users.go")); !os.IsNotExist(err) {
+		t.Errorf("Expected users.go not to be written, got %v", err)
+	}
+
+	// Overwriting replaces the existing file, whose declarations no longer count
+	paths, err := WriteModels(dir, usersSchema(), true)
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("Expected both models to be written, got %v, %v", paths, err)
+	}
+}
+
+func TestGenerateModels_InverseRelationPerForeignKey(t *testing.T) {
+	users := Table{Name: "users", Columns: []Column{{Name: "id", Type: "CHAR(36)", PrimaryKey: true}}}
+	posts := Table{
+		Name: "posts",
+		Columns: []Column{
+			{Name: "id", Type: "CHAR(36)", PrimaryKey: true},
+			{Name: "author_id", Type: "CHAR(36)"},
+			{Name: "editor_id", Type: "CHAR(36)", Nullable: true},
+		},
+		ForeignKeys: []ForeignKey{
+			{Name: "posts_author", Column: "author_id", RefTable: "users", RefColumn: "id"},
+			{Name: "posts_editor", Column: "editor_id", RefTable: "users", RefColumn: "id"},
+		},
+	}
+	files, err := GenerateModels([]Table{users, posts}, "models")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"users": {
+			"AuthorPosts []Posts `json:\"author_posts,omitempty\" gorm:\"foreignKey:AuthorID\"`",
+			"EditorPosts []Posts `json:\"editor_posts,omitempty\" gorm:\"foreignKey:EditorID\"`",
+		},
+		"posts": {
+			"Author *Users `json:\"author,omitempty\" gorm:\"foreignKey:AuthorID\"`",
+			"Editor *Users `json:\"editor,omitempty\" gorm:\"foreignKey:EditorID\"`",
+		},
+	}
+	for table, lines := range expected {
+		source := string(files[table])
+		for _, line := range lines {
+			if !strings.Contains(source, line) {
+				t.Errorf("Expected the %s model to contain %q, got:\n%s", table, line, source)
+			}
+		}
+	}
+	if strings.Contains(string(files["users"]), "\tPosts ") {
+		t.Errorf("Expected no ambiguous Posts field on users, got:\n%s", files["users"])
+	}
+}
@@ -0,0 +1,352 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ModelProvider is implemented by modules whose model structs describe their tables
+type ModelProvider interface {
+	Models() []any
+}
+
+// tabler is implemented by models that override their table name, as in gorm
+type tabler interface {
+	TableName() string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// FromModels derives MySQL table definitions from model structs and their gorm tags.
+// Supported tags: column, type, size, primaryKey, autoIncrement, not null, default,
+// uniqueIndex, index, autoCreateTime, autoUpdateTime, foreignKey, references and
+// constraint:OnDelete/OnUpdate. Association fields become foreign keys.
+func FromModels(models []any) ([]Table, error) {
+	byType := make(map[reflect.Type]*Table)
+	tables := make([]*Table, 0, len(models))
+
+	for _, model := range models {
+		t := structType(model)
+		if t == nil {
+			return nil, fmt.Errorf("model %T is not a struct", model)
+		}
+		table := &Table{Name: TableName(model)}
+		byType[t] = table
+		tables = append(tables, table)
+	}
+
+	for _, model := range models {
+		t := structType(model)
+		table := byType[t]
+
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			tag := parseTag(field.Tag.Get("gorm"))
+			if _, skip := tag["-"]; skip {
+				continue
+			}
+
+			if related := associationType(field.Type); related != nil {
+				if err := addAssociation(t, table, field, tag, related, byType); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			column, err := columnFromField(field, tag)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", t.Name(), field.Name, err)
+			}
+			table.Columns = append(table.Columns, column)
+
+			if _, ok := tag["uniqueindex"]; ok {
+				table.Indexes = append(table.Indexes, Index{Name: indexName(tag["uniqueindex"], table.Name, column.Name), Columns: []string{column.Name}, Unique: true})
+			} else if _, ok := tag["index"]; ok {
+				table.Indexes = append(table.Indexes, Index{Name: indexName(tag["index"], table.Name, column.Name), Columns: []string{column.Name}})
+			}
+		}
+	}
+
+	result := make([]Table, 0, len(tables))
+	for _, table := range tables {
+		result = append(result, *table)
+	}
+	return result, nil
+}
+
+// TableName returns the table of a model: its TableName method, or the snake_case plural of its type name
+func TableName(model any) string {
+	if named, ok := model.(tabler); ok {
+		return named.TableName()
+	}
+	name := SnakeCase(structType(model).Name())
+	if !strings.HasSuffix(name, "s") {
+		name += "s"
+	}
+	return name
+}
+
+// SnakeCase converts a Go identifier such as UserID to user_id
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			startsWord := i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+			if startsWord {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// structType returns the struct type behind a model value or pointer
+func structType(model any) reflect.Type {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// associationType returns the related struct type for association fields (structs, pointers and slices of structs)
+func associationType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Struct && t != timeType {
+		return t
+	}
+	return nil
+}
+
+// addAssociation records the foreign key implied by an association field.
+// A belongs-to field's key lives on this table; a has-one/has-many key lives on the related table.
+func addAssociation(owner reflect.Type, table *Table, field reflect.StructField, tag map[string]string, related reflect.Type, byType map[reflect.Type]*Table) error {
+	relatedTable, known := byType[related]
+	if !known {
+		return nil
+	}
+
+	keyField := tag["foreignkey"]
+	if keyField == "" {
+		keyField = field.Name + "ID"
+	}
+	onDelete, onUpdate := constraintRules(tag["constraint"])
+
+	if keyOwner, ok := owner.FieldByName(keyField); ok {
+		// belongs-to: this table holds the key
+		key := ForeignKey{
+			Column:    columnName(keyOwner),
+			RefTable:  relatedTable.Name,
+			RefColumn: referenceColumn(tag, relatedTable),
+			OnDelete:  onDelete,
+			OnUpdate:  onUpdate,
+		}
+		key.Name = fmt.Sprintf("fk_%s_%s", table.Name, key.Column)
+		addForeignKey(table, key)
+		return nil
+	}
+
+	if keyOwner, ok := related.FieldByName(keyField); ok {
+		// has-one or has-many: the related table holds the key
+		key := ForeignKey{
+			Column:    columnName(keyOwner),
+			RefTable:  table.Name,
+			RefColumn: referenceColumn(tag, table),
+			OnDelete:  onDelete,
+			OnUpdate:  onUpdate,
+		}
+		key.Name = fmt.Sprintf("fk_%s_%s", relatedTable.Name, key.Column)
+		addForeignKey(relatedTable, key)
+		return nil
+	}
+
+	return fmt.Errorf("%s.%s: foreign key field %s not found", owner.Name(), field.Name, keyField)
+}
+
+// addForeignKey adds a key unless the same column already references a table, keeping explicit rules
+func addForeignKey(table *Table, key ForeignKey) {
+	for i, existing := range table.ForeignKeys {
+		if existing.Column == key.Column {
+			if existing.OnDelete == "" {
+				table.ForeignKeys[i].OnDelete = key.OnDelete
+			}
+			if existing.OnUpdate == "" {
+				table.ForeignKeys[i].OnUpdate = key.OnUpdate
+			}
+			return
+		}
+	}
+	table.ForeignKeys = append(table.ForeignKeys, key)
+}
+
+// referenceColumn returns the referenced column of an association, defaulting to the primary key
+func referenceColumn(tag map[string]string, table *Table) string {
+	if ref := tag["references"]; ref != "" {
+		return SnakeCase(ref)
+	}
+	if keys := table.PrimaryKey(); len(keys) == 1 {
+		return keys[0]
+	}
+	return "id"
+}
+
+// constraintRules parses constraint:OnDelete:CASCADE,OnUpdate:SET NULL
+func constraintRules(constraint string) (onDelete, onUpdate string) {
+	for _, part := range strings.Split(constraint, ",") {
+		rule := strings.SplitN(part, ":", 2)
+		if len(rule) != 2 {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(rule[0])) {
+		case "ondelete":
+			onDelete = strings.ToUpper(strings.TrimSpace(rule[1]))
+		case "onupdate":
+			onUpdate = strings.ToUpper(strings.TrimSpace(rule[1]))
+		}
+	}
+	return onDelete, onUpdate
+}
+
+// columnName returns the column of a field: its column tag or the snake_case field name
+func columnName(field reflect.StructField) string {
+	if name := parseTag(field.Tag.Get("gorm"))["column"]; name != "" {
+		return name
+	}
+	return SnakeCase(field.Name)
+}
+
+// columnFromField builds the column definition of a scalar field
+func columnFromField(field reflect.StructField, tag map[string]string) (Column, error) {
+	column := Column{Name: columnName(field)}
+
+	t := field.Type
+	pointer := t.Kind() == reflect.Ptr
+	if pointer {
+		t = t.Elem()
+	}
+
+	_, primaryKey := tag["primarykey"]
+	_, notNull := tag["not null"]
+	_, autoIncrement := tag["autoincrement"]
+	_, autoCreate := tag["autocreatetime"]
+	_, autoUpdate := tag["autoupdatetime"]
+
+	column.PrimaryKey = primaryKey
+	column.AutoIncrement = autoIncrement
+	column.Nullable = !primaryKey && !notNull && !autoCreate && !autoUpdate && (pointer || t.Kind() == reflect.String || t == timeType)
+
+	sqlType, err := mysqlType(t, tag)
+	if err != nil {
+		return column, err
+	}
+	column.Type = sqlType
+
+	if value, ok := tag["default"]; ok {
+		expr := defaultFromTag(value)
+		column.Default = &expr
+	}
+	if autoCreate || autoUpdate {
+		expr := "CURRENT_TIMESTAMP"
+		column.Default = &expr
+	}
+	if autoUpdate {
+		column.OnUpdate = "CURRENT_TIMESTAMP"
+	}
+	return column, nil
+}
+
+// mysqlType maps a Go type and its gorm tags to a MySQL column type
+func mysqlType(t reflect.Type, tag map[string]string) (string, error) {
+	if explicit := tag["type"]; explicit != "" {
+		switch strings.ToLower(explicit) {
+		case "uuid":
+			return "CHAR(36)", nil
+		case "jsonb":
+			return "JSON", nil
+		}
+		return strings.ToUpper(explicit), nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if size := tag["size"]; size != "" {
+			return "VARCHAR(" + size + ")", nil
+		}
+		return "VARCHAR(255)", nil
+	case reflect.Bool:
+		return "TINYINT(1)", nil
+	case reflect.Int8, reflect.Uint8:
+		return "TINYINT", nil
+	case reflect.Int16, reflect.Uint16:
+		return "SMALLINT", nil
+	case reflect.Int, reflect.Int32, reflect.Uint, reflect.Uint32:
+		return "INT", nil
+	case reflect.Int64, reflect.Uint64:
+		return "BIGINT", nil
+	case reflect.Float32:
+		return "FLOAT", nil
+	case reflect.Float64:
+		return "DOUBLE", nil
+	case reflect.Struct:
+		if t == timeType {
+			return "TIMESTAMP", nil
+		}
+	}
+	return "", fmt.Errorf("no MySQL type for %s; add a type tag", t)
+}
+
+// defaultFromTag converts a gorm default into a MySQL default expression
+func defaultFromTag(value string) string {
+	switch strings.ToLower(value) {
+	case "uuid_generate_v4()", "gen_random_uuid()", "uuid()", "(uuid())":
+		return "(UUID())"
+	case "now()", "current_timestamp", "current_timestamp()":
+		return "CURRENT_TIMESTAMP"
+	case "true":
+		return "1"
+	case "false":
+		return "0"
+	}
+	return value
+}
+
+// indexName returns the explicit index name from a tag or a conventional one
+func indexName(explicit, table, column string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return fmt.Sprintf("idx_%s_%s", table, column)
+}
+
+// parseTag splits a gorm tag into lower-cased keys and their values
+func parseTag(tag string) map[string]string {
+	values := make(map[string]string)
+	for _, part := range strings.Split(tag, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, ":", 2)
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		value := ""
+		if len(kv) == 2 {
+			value = strings.TrimSpace(kv[1])
+		}
+		values[key] = value
+	}
+	return values
+}
@@ -0,0 +1,200 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Table describes a database table
+type Table struct {
+	Name        string
+	Columns     []Column
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+}
+
+// Column describes a table column. Default holds a SQL expression such as 'email',
+// CURRENT_TIMESTAMP or (UUID()), and is nil when the column has no default.
+type Column struct {
+	Name          string
+	Type          string
+	Nullable      bool
+	Default       *string
+	OnUpdate      string
+	AutoIncrement bool
+	PrimaryKey    bool
+}
+
+// Index describes a secondary index; primary keys are recorded on the columns
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// ForeignKey describes a single-column foreign key constraint
+type ForeignKey struct {
+	Name      string
+	Column    string
+	RefTable  string
+	RefColumn string
+	OnDelete  string
+	OnUpdate  string
+}
+
+// Column returns the column with the given name
+func (t Table) Column(name string) (Column, bool) {
+	for _, column := range t.Columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+	return Column{}, false
+}
+
+// PrimaryKey returns the names of the primary key columns
+func (t Table) PrimaryKey() []string {
+	var names []string
+	for _, column := range t.Columns {
+		if column.PrimaryKey {
+			names = append(names, column.Name)
+		}
+	}
+	return names
+}
+
+// TableExists reports whether a table exists in the current database
+func TableExists(db *sql.DB, name string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", name).Scan(&count)
+	return count > 0, err
+}
+
+// Inspect reads the definition of the given tables from information_schema.
+// Tables that don't exist are left out of the result.
+func Inspect(db *sql.DB, names []string) ([]Table, error) {
+	var tables []Table
+	for _, name := range names {
+		exists, err := TableExists(db, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up table %s: %v", name, err)
+		}
+		if !exists {
+			continue
+		}
+
+		table := Table{Name: name}
+		if table.Columns, err = inspectColumns(db, name); err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %v", name, err)
+		}
+		if table.Indexes, err = inspectIndexes(db, name); err != nil {
+			return nil, fmt.Errorf("failed to read indexes of %s: %v", name, err)
+		}
+		if table.ForeignKeys, err = inspectForeignKeys(db, name); err != nil {
+			return nil, fmt.Errorf("failed to read foreign keys of %s: %v", name, err)
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// inspectColumns reads the columns of a table in ordinal order
+func inspectColumns(db *sql.DB, table string) ([]Column, error) {
+	rows, err := db.Query("SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA, COLUMN_KEY, DATA_TYPE "+
+		"FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var column Column
+		var nullable, extra, key, dataType string
+		var def sql.NullString
+		if err := rows.Scan(&column.Name, &column.Type, &nullable, &def, &extra, &key, &dataType); err != nil {
+			return nil, err
+		}
+
+		extra = strings.ToLower(extra)
+		column.Type = strings.ToUpper(column.Type)
+		column.Nullable = nullable == "YES"
+		column.PrimaryKey = key == "PRI"
+		column.AutoIncrement = strings.Contains(extra, "auto_increment")
+		if strings.Contains(extra, "on update current_timestamp") {
+			column.OnUpdate = "CURRENT_TIMESTAMP"
+		}
+		if def.Valid {
+			expr := defaultExpression(def.String, dataType, strings.Contains(extra, "default_generated"))
+			column.Default = &expr
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// defaultExpression turns an information_schema default into the SQL that would declare it
+func defaultExpression(value, dataType string, generated bool) string {
+	upper := strings.ToUpper(value)
+	switch {
+	case strings.HasPrefix(upper, "CURRENT_TIMESTAMP"):
+		return "CURRENT_TIMESTAMP"
+	case generated:
+		return "(" + value + ")"
+	}
+
+	switch strings.ToLower(dataType) {
+	case "tinyint", "smallint", "mediumint", "int", "bigint", "decimal", "float", "double", "bit":
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// inspectIndexes reads the secondary indexes of a table
+func inspectIndexes(db *sql.DB, table string) ([]Index, error) {
+	rows, err := db.Query("SELECT INDEX_NAME, NON_UNIQUE, COLUMN_NAME FROM information_schema.STATISTICS "+
+		"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME <> 'PRIMARY' ORDER BY INDEX_NAME, SEQ_IN_INDEX", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var name, column string
+		var nonUnique int
+		if err := rows.Scan(&name, &nonUnique, &column); err != nil {
+			return nil, err
+		}
+		if n := len(indexes); n > 0 && indexes[n-1].Name == name {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+			continue
+		}
+		indexes = append(indexes, Index{Name: name, Columns: []string{column}, Unique: nonUnique == 0})
+	}
+	return indexes, rows.Err()
+}
+
+// inspectForeignKeys reads the foreign keys declared on a table
+func inspectForeignKeys(db *sql.DB, table string) ([]ForeignKey, error) {
+	rows, err := db.Query("SELECT k.CONSTRAINT_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.DELETE_RULE, r.UPDATE_RULE "+
+		"FROM information_schema.KEY_COLUMN_USAGE k JOIN information_schema.REFERENTIAL_CONSTRAINTS r "+
+		"ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME "+
+		"WHERE k.TABLE_SCHEMA = DATABASE() AND k.TABLE_NAME = ? AND k.REFERENCED_TABLE_NAME IS NOT NULL "+
+		"ORDER BY k.CONSTRAINT_NAME, k.ORDINAL_POSITION", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []ForeignKey
+	for rows.Next() {
+		var key ForeignKey
+		if err := rows.Scan(&key.Name, &key.Column, &key.RefTable, &key.RefColumn, &key.OnDelete, &key.OnUpdate); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
@@ -0,0 +1,245 @@
+package seeds
+
+import (
+	"auto_verse/app"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Environments that seeds can be written for
+var Environments = []string{"dev", "test", "demo"}
+
+// SeedTable records which seeds have been applied to the database
+const SeedTable = "schema_seeds"
+
+// Provider is implemented by modules that ship SQL seed files, laid out as <env>/<name>.sql
+type Provider interface {
+	Seeds() fs.FS
+}
+
+// SeedFunc loads data into the database
+type SeedFunc func(db *sql.DB) error
+
+// Seed is a seed written in Go
+type Seed struct {
+	Module       string   // Module the seed belongs to
+	Name         string   // Unique name within the module; seeds run in name order with the SQL files
+	Environments []string // Environments the seed runs in
+	Run          SeedFunc // Loads the data; must be safe to run more than once
+	// Version identifies the data the seed loads; change it to run the seed again where it already ran
+	Version string
+}
+
+var (
+	registry = make(map[string][]Seed)
+	mu       sync.Mutex
+)
+
+// Register adds a Go seed to the registry; call it from the module's init function
+func Register(seed Seed) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if seed.Module == "" || seed.Name == "" || seed.Run == nil || len(seed.Environments) == 0 {
+		panic(fmt.Sprintf("seed %q must have a module, a name, environments and a run function", seed.Name))
+	}
+	for _, env := range seed.Environments {
+		if !validEnvironment(env) {
+			panic(fmt.Sprintf("seed %s of module %s has unknown environment %s", seed.Name, seed.Module, env))
+		}
+	}
+	registry[seed.Module] = append(registry[seed.Module], seed)
+}
+
+// Result describes what happened to one seed
+type Result struct {
+	Module  string `json:"module"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"` // false when the seed had already run unchanged
+}
+
+// RunAll seeds every registered module for an environment, dependencies first
+func RunAll(db *sql.DB, env string) ([]Result, error) {
+	modules, err := app.Ordered()
+	if err != nil {
+		return nil, fmt.Errorf("failed to order modules: %v", err)
+	}
+	return run(db, modules, env)
+}
+
+// RunForModule seeds a single module for an environment
+func RunForModule(db *sql.DB, moduleName, env string) ([]Result, error) {
+	module, err := app.Get(moduleName)
+	if err != nil {
+		return nil, err
+	}
+	return run(db, []app.Module{module}, env)
+}
+
+// seed is a SQL file or Go seed ready to run
+type seed struct {
+	name     string
+	sql      string
+	fn       SeedFunc
+	checksum string
+}
+
+// run applies the seeds of the given modules, skipping those already applied with the same content
+func run(db *sql.DB, modules []app.Module, env string) ([]Result, error) {
+	if !validEnvironment(env) {
+		return nil, fmt.Errorf("invalid seed environment: %s (expected one of %s)", env, strings.Join(Environments, ", "))
+	}
+	if err := ensureSeedTable(db); err != nil {
+		return nil, fmt.Errorf("failed to create seed table: %v", err)
+	}
+
+	var results []Result
+	for _, module := range modules {
+		list, err := moduleSeeds(module, env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load seeds for module %s: %v", module.Name(), err)
+		}
+
+		for _, s := range list {
+			result := Result{Module: module.Name(), Name: s.name}
+
+			applied, err := alreadyApplied(db, module.Name(), env, s)
+			if err != nil {
+				return nil, err
+			}
+			if !applied {
+				if err := apply(db, s); err != nil {
+					return nil, fmt.Errorf("seed %s of module %s failed: %v", s.name, module.Name(), err)
+				}
+				if err := record(db, module.Name(), env, s); err != nil {
+					return nil, fmt.Errorf("failed to record seed %s of module %s: %v", s.name, module.Name(), err)
+				}
+				result.Applied = true
+				log.Printf("Seeded %s/%s (%s)", module.Name(), s.name, env)
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// moduleSeeds collects the SQL files and Go seeds of a module for an environment, ordered by name
+func moduleSeeds(module app.Module, env string) ([]seed, error) {
+	var list []seed
+
+	if provider, ok := module.(Provider); ok && provider.Seeds() != nil {
+		fsys := provider.Seeds()
+		entries, err := fs.ReadDir(fsys, env)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+				continue
+			}
+			content, err := fs.ReadFile(fsys, path.Join(env, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, seed{
+				name:     entry.Name(),
+				sql:      string(content),
+				checksum: checksum(content),
+			})
+		}
+	}
+
+	mu.Lock()
+	for _, registered := range registry[module.Name()] {
+		for _, seedEnv := range registered.Environments {
+			if seedEnv == env {
+				// Go code can't be hashed, so its version stands in for the content
+				list = append(list, seed{
+					name:     registered.Name,
+					fn:       registered.Run,
+					checksum: checksum([]byte("go:" + registered.Name + ":" + registered.Version)),
+				})
+			}
+		}
+	}
+	mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list, nil
+}
+
+// checksum returns the hex encoded SHA-256 of a seed's content
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// apply runs a seed inside a transaction where possible
+func apply(db *sql.DB, s seed) error {
+	if s.fn != nil {
+		return s.fn(db)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.sql); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ensureSeedTable creates the seed tracking table if it doesn't exist
+func ensureSeedTable(db *sql.DB) error {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS `" + SeedTable + "` (" +
+		"module VARCHAR(64) NOT NULL, " +
+		"environment VARCHAR(16) NOT NULL, " +
+		"name VARCHAR(255) NOT NULL, " +
+		"checksum CHAR(64) NOT NULL DEFAULT '', " +
+		"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, " +
+		"PRIMARY KEY (module, environment, name))")
+	return err
+}
+
+// alreadyApplied reports whether a seed ran before with the same content
+func alreadyApplied(db *sql.DB, moduleName, env string, s seed) (bool, error) {
+	var recorded string
+	err := db.QueryRow("SELECT checksum FROM `"+SeedTable+"` WHERE module = ? AND environment = ? AND name = ?",
+		moduleName, env, s.name).Scan(&recorded)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read seed state: %v", err)
+	}
+	return recorded == s.checksum, nil
+}
+
+// record marks a seed as applied
+func record(db *sql.DB, moduleName, env string, s seed) error {
+	_, err := db.Exec("INSERT INTO `"+SeedTable+"` (module, environment, name, checksum) VALUES (?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE checksum = VALUES(checksum), applied_at = CURRENT_TIMESTAMP",
+		moduleName, env, s.name, s.checksum)
+	return err
+}
+
+// validEnvironment reports whether env is a known seed environment
+func validEnvironment(env string) bool {
+	for _, known := range Environments {
+		if env == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package seeds
+
+import (
+	"auto_verse/app"
+	"database/sql"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+type testModule struct {
+	name  string
+	seeds fstest.MapFS
+}
+
+func (m testModule) Name() string                      { return m.name }
+func (m testModule) DependsOn() []string               { return nil }
+func (m testModule) RegisterRoutes(router *app.Router) {}
+func (m testModule) Migrations() fs.FS                 { return nil }
+func (m testModule) MigrationsDir() string             { return "" }
+func (m testModule) Init(db *sql.DB) error             { return nil }
+func (m testModule) Shutdown() error                   { return nil }
+func (m testModule) Seeds() fs.FS                      { return m.seeds }
+
+func noop(db *sql.DB) error { return nil }
+
+func TestModuleSeeds_OrdersSQLAndGoSeedsByName(t *testing.T) {
+	module := testModule{name: "seeds_order_test", seeds: fstest.MapFS{
+		"dev/01_roles.sql": {Data: []byte("INSERT INTO roles VALUES (1, 'admin');")},
+		"dev/03_posts.sql": {Data: []byte("INSERT INTO posts VALUES (1, 'hello');")},
+		"dev/notes.txt":    {Data: []byte("not a seed")},
+		"demo/01_demo.sql": {Data: []byte("INSERT INTO roles VALUES (2, 'demo');")},
+	}}
+	Register(Seed{Module: module.name, Name: "02_users", Environments: []string{"dev", "test"}, Run: noop})
+	Register(Seed{Module: module.name, Name: "00_demo_users", Environments: []string{"demo"}, Run: noop})
+
+	tests := []struct {
+		env  string
+		want []string
+	}{
+		{"dev", []string{"01_roles.sql", "02_users", "03_posts.sql"}},
+		{"test", []string{"02_users"}},
+		{"demo", []string{"00_demo_users", "01_demo.sql"}},
+	}
+	for _, tt := range tests {
+		list, err := moduleSeeds(module, tt.env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, s := range list {
+			names = append(names, s.name)
+		}
+		if len(names) != len(tt.want) {
+			t.Errorf("Expected %s seeds %v, got %v", tt.env, tt.want, names)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.want[i] {
+				t.Errorf("Expected %s seeds %v, got %v", tt.env, tt.want, names)
+				break
+			}
+		}
+	}
+}
+
+func TestModuleSeeds_GoSeedChecksumFollowsVersion(t *testing.T) {
+	first := testModule{name: "seeds_version_test"}
+	Register(Seed{Module: first.name, Name: "users", Environments: []string{"dev"}, Run: noop, Version: "1"})
+	second := testModule{name: "seeds_version_bumped_test"}
+	Register(Seed{Module: second.name, Name: "users", Environments: []string{"dev"}, Run: noop, Version: "2"})
+
+	before, err := moduleSeeds(first, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := moduleSeeds(second, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before[0].checksum == "" {
+		t.Error("Expected Go seeds to have a checksum")
+	}
+	if before[0].checksum == after[0].checksum {
+		t.Error("Expected a new version to change the checksum so the seed runs again")
+	}
+}
+
+func TestRegister_PanicsOnInvalidSeeds(t *testing.T) {
+	tests := map[string]Seed{
+		"missing module":       {Name: "users", Environments: []string{"dev"}, Run: noop},
+		"missing name":         {Module: "users", Environments: []string{"dev"}, Run: noop},
+		"missing run":          {Module: "users", Name: "users", Environments: []string{"dev"}},
+		"missing environments": {Module: "users", Name: "users", Run: noop},
+		"unknown environment":  {Module: "users", Name: "users", Environments: []string{"prod"}, Run: noop},
+	}
+	for name, seed := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected Register to panic")
+				}
+			}()
+			Register(seed)
+		})
+	}
+}